@@ -4,9 +4,13 @@ import (
 	"ai-language-notes/internal/ai"
 	"ai-language-notes/internal/api"
 	"ai-language-notes/internal/config"
+	"ai-language-notes/internal/events"
+	"ai-language-notes/internal/prompts"
 	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/quota"
 	"ai-language-notes/internal/repository"
 	"ai-language-notes/internal/storage"
+	"ai-language-notes/internal/webhook"
 	"ai-language-notes/internal/worker"
 	"context"
 	"log"
@@ -30,47 +34,125 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
-	// Init Redis
+	// Init Redis. It is always needed: note events and cancellations cross
+	// between the API and the workers on its pub/sub, whichever queue backend
+	// is used
 	redisClient, err := storage.InitRedis(&cfg)
 	if err != nil {
-		log.Fatalf("FATAL: Could not initialize Redis: %v\n", err)
+		log.Fatalf("FATAL: Could not initialize Redis, required for the event bus: %v\n", err)
 	}
 	defer redisClient.Close()
 
 	// Initialize repositories using the proper implementations
 	userRepo := repository.NewUserRepository(pgStore)
 	noteRepo := repository.NewNoteRepository(pgStore)
+	webhookRepo := repository.NewWebhookRepository(pgStore)
+	usageRepo := repository.NewUsageRepository(pgStore)
+
+	// Enforce the quotas of the users' plans
+	var quotaLimiter quota.Limiter
+	if cfg.EnableQuotas {
+		plans, err := quota.ParsePlans(cfg.PlanQuotas)
+		if err != nil {
+			log.Fatalf("Failed to parse plan quotas: %v", err)
+		}
+		quotaLimiter = quota.NewRedisLimiter(redisClient, plans)
+	}
 
-	// Initialize the AI service using factory
-	var apiKey string
-	if cfg.LLMProvider == "openai" {
-		apiKey = cfg.OpenAIAPIKey
-	} else {
-		apiKey = cfg.DeepSeekAPIKey
+	// Load the prompt templates, watching PROMPTS_DIR for changes
+	promptVersions, err := cfg.PromptVersionPins()
+	if err != nil {
+		log.Fatalf("Failed to parse prompt versions: %v", err)
 	}
+	promptRegistry, err := prompts.NewRegistry(prompts.Config{
+		Dir:            cfg.PromptsDir,
+		ReloadInterval: cfg.PromptReloadInterval,
+		Versions:       promptVersions,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	promptRegistry.Start()
+	defer promptRegistry.Stop()
 
-	llmService, err := ai.CreateLLMServiceFromConfig(cfg.LLMProvider, apiKey)
+	// Initialize the AI service using factory, falling back through the
+	// configured providers in order
+	llmService, err := ai.CreateLLMServiceFromAppConfig(cfg, promptRegistry)
 	if err != nil {
 		log.Fatalf("Failed to initialize LLM service: %v", err)
 	}
+	if cfg.EnableCache {
+		llmService = ai.NewCachedLLMService(llmService, redisClient, cfg.LLMCacheTTL, promptRegistry)
+	}
 
-	// Initialize queue service
-	queueService := queue.NewQueueService(redisClient)
-
-	// Initialize worker service (with 3 worker goroutines)
-	workerService := worker.NewWorker(
-		queueService,
-		noteRepo,
-		userRepo,
-		llmService,
-		cfg.WorkerCount,
-	)
+	// Initialize the event bus, shared with the other processes through Redis
+	bus := events.NewRedisBus(redisClient)
+
+	// Initialize queue service for the configured backend
+	queueService, err := queue.NewQueue(queue.Config{
+		Backend:           queue.BackendType(cfg.QueueBackend),
+		RedisClient:       redisClient,
+		DB:                pgStore.GetDB(),
+		VisibilityTimeout: cfg.QueueVisibilityTimeout,
+		DedupTTL:          cfg.QueueDedupTTL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize queue: %v", err)
+	}
 
-	// Start the worker service
-	workerService.Start()
+	// Run the worker pool in-process unless it is deployed separately (cmd/worker)
+	var workerService *worker.Worker
+	var reconciler *worker.Reconciler
+	var webhookDispatcher *webhook.Dispatcher
+	if cfg.EmbeddedWorkers {
+		// Send webhook deliveries queued by the workers
+		webhookDispatcher = webhook.NewDispatcher(webhookRepo, webhook.Config{
+			Timeout:              cfg.WebhookTimeout,
+			MaxAttempts:          cfg.WebhookMaxAttempts,
+			BaseDelay:            cfg.WebhookBaseDelay,
+			MaxDelay:             cfg.WebhookMaxDelay,
+			AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+		})
+		webhookDispatcher.Start()
+
+		workerService = worker.NewWorker(
+			queueService,
+			noteRepo,
+			userRepo,
+			usageRepo,
+			quotaLimiter,
+			llmService,
+			bus,
+			webhookDispatcher,
+			worker.Config{
+				WorkerCount:       cfg.WorkerCount,
+				HeartbeatInterval: cfg.QueueVisibilityTimeout / 3,
+				ReaperInterval:    cfg.QueueReaperInterval,
+				MaxAttempts:       cfg.TaskMaxAttempts,
+				RetryBaseDelay:    cfg.RetryBaseDelay,
+				RetryMaxDelay:     cfg.RetryMaxDelay,
+				DrainTimeout:      cfg.WorkerDrainTimeout,
+			},
+		)
+		workerService.Start()
+
+		// Recover notes stuck in pending or processing, now and periodically
+		reconciler = worker.NewReconciler(
+			queueService,
+			noteRepo,
+			userRepo,
+			worker.ReconcilerConfig{
+				Interval:   cfg.ReconcileInterval,
+				StaleAfter: cfg.ReconcileStaleAfter,
+			},
+		)
+		reconciler.Start()
+	} else {
+		log.Println("Embedded workers disabled, notes are processed by cmd/worker")
+	}
 
 	// Setup router with repositories and services
-	router := api.SetupRouter(cfg, userRepo, noteRepo, llmService, queueService)
+	router := api.SetupRouter(cfg, userRepo, noteRepo, webhookRepo, usageRepo, quotaLimiter, llmService, queueService, bus)
 
 	// Configure HTTP server
 	srv := &http.Server{
@@ -93,7 +175,11 @@ func main() {
 	log.Println("Shutting down server...")
 
 	// Shutdown worker first
-	workerService.Stop()
+	if cfg.EmbeddedWorkers {
+		reconciler.Stop()
+		workerService.Stop()
+		webhookDispatcher.Stop()
+	}
 
 	// Create a deadline to wait for current operations to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"ai-language-notes/internal/ai"
+	"ai-language-notes/internal/config"
+	"ai-language-notes/internal/events"
+	"ai-language-notes/internal/prompts"
+	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/quota"
+	"ai-language-notes/internal/repository"
+	"ai-language-notes/internal/storage"
+	"ai-language-notes/internal/webhook"
+	"ai-language-notes/internal/worker"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// main runs the LLM worker pool without the API server, so workers can be
+// scaled independently of API pods
+func main() {
+	// Load configuration
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database connection
+	pgStore, err := storage.NewPostgresStorage(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	// Init Redis. It is always needed: note events and cancellations cross
+	// between the API and the workers on its pub/sub, whichever queue backend
+	// is used
+	redisClient, err := storage.InitRedis(&cfg)
+	if err != nil {
+		log.Fatalf("FATAL: Could not initialize Redis, required for the event bus: %v\n", err)
+	}
+	defer redisClient.Close()
+
+	// Initialize repositories
+	userRepo := repository.NewUserRepository(pgStore)
+	noteRepo := repository.NewNoteRepository(pgStore)
+	webhookRepo := repository.NewWebhookRepository(pgStore)
+	usageRepo := repository.NewUsageRepository(pgStore)
+
+	// Enforce the quotas of the users' plans
+	var quotaLimiter quota.Limiter
+	if cfg.EnableQuotas {
+		plans, err := quota.ParsePlans(cfg.PlanQuotas)
+		if err != nil {
+			log.Fatalf("Failed to parse plan quotas: %v", err)
+		}
+		quotaLimiter = quota.NewRedisLimiter(redisClient, plans)
+	}
+
+	// Load the prompt templates, watching PROMPTS_DIR for changes
+	promptVersions, err := cfg.PromptVersionPins()
+	if err != nil {
+		log.Fatalf("Failed to parse prompt versions: %v", err)
+	}
+	promptRegistry, err := prompts.NewRegistry(prompts.Config{
+		Dir:            cfg.PromptsDir,
+		ReloadInterval: cfg.PromptReloadInterval,
+		Versions:       promptVersions,
+	})
+	if err != nil {
+		log.Fatalf("Failed to load prompt templates: %v", err)
+	}
+	promptRegistry.Start()
+	defer promptRegistry.Stop()
+
+	// Initialize the AI service using factory, falling back through the
+	// configured providers in order
+	llmService, err := ai.CreateLLMServiceFromAppConfig(cfg, promptRegistry)
+	if err != nil {
+		log.Fatalf("Failed to initialize LLM service: %v", err)
+	}
+	if cfg.EnableCache {
+		llmService = ai.NewCachedLLMService(llmService, redisClient, cfg.LLMCacheTTL, promptRegistry)
+	}
+
+	// Initialize the event bus, shared with the other processes through Redis
+	bus := events.NewRedisBus(redisClient)
+
+	// Initialize queue service for the configured backend
+	queueService, err := queue.NewQueue(queue.Config{
+		Backend:           queue.BackendType(cfg.QueueBackend),
+		RedisClient:       redisClient,
+		DB:                pgStore.GetDB(),
+		VisibilityTimeout: cfg.QueueVisibilityTimeout,
+		DedupTTL:          cfg.QueueDedupTTL,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize queue: %v", err)
+	}
+	if queue.BackendType(cfg.QueueBackend) == queue.BackendMemory {
+		log.Println("WARNING: the memory queue backend isn't shared with the API process, this worker will never receive tasks")
+	}
+
+	// Send webhook deliveries queued by the workers
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhook.Config{
+		Timeout:              cfg.WebhookTimeout,
+		MaxAttempts:          cfg.WebhookMaxAttempts,
+		BaseDelay:            cfg.WebhookBaseDelay,
+		MaxDelay:             cfg.WebhookMaxDelay,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+	webhookDispatcher.Start()
+
+	workerService := worker.NewWorker(
+		queueService,
+		noteRepo,
+		userRepo,
+		usageRepo,
+		quotaLimiter,
+		llmService,
+		bus,
+		webhookDispatcher,
+		worker.Config{
+			WorkerCount:       cfg.WorkerCount,
+			HeartbeatInterval: cfg.QueueVisibilityTimeout / 3,
+			ReaperInterval:    cfg.QueueReaperInterval,
+			MaxAttempts:       cfg.TaskMaxAttempts,
+			RetryBaseDelay:    cfg.RetryBaseDelay,
+			RetryMaxDelay:     cfg.RetryMaxDelay,
+			DrainTimeout:      cfg.WorkerDrainTimeout,
+		},
+	)
+	workerService.Start()
+
+	// Recover notes stuck in pending or processing, now and periodically
+	reconciler := worker.NewReconciler(
+		queueService,
+		noteRepo,
+		userRepo,
+		worker.ReconcilerConfig{
+			Interval:   cfg.ReconcileInterval,
+			StaleAfter: cfg.ReconcileStaleAfter,
+		},
+	)
+	reconciler.Start()
+
+	// Health and metrics endpoint
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"UP"}`))
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:    ":" + cfg.WorkerHTTPPort,
+		Handler: mux,
+	}
+
+	go func() {
+		log.Printf("Worker health server starting on port %s", cfg.WorkerHTTPPort)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start health server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down worker...")
+
+	reconciler.Stop()
+	workerService.Stop()
+	webhookDispatcher.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Fatal("Health server forced to shutdown:", err)
+	}
+
+	log.Println("Worker exited gracefully")
+}
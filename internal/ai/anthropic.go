@@ -0,0 +1,282 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Anthropic API defaults
+const (
+	DefaultAnthropicBaseURL   = "https://api.anthropic.com"
+	DefaultAnthropicModel     = "claude-3-5-sonnet-latest"
+	DefaultAnthropicVersion   = "2023-06-01"
+	DefaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicService implements LLMService using Anthropic's Messages API
+type AnthropicService struct {
+	BaseClient *BaseLLMClient
+	MaxTokens  int
+}
+
+// AnthropicMessagesRequest represents a request to the Anthropic Messages API
+type AnthropicMessagesRequest struct {
+	Model      string               `json:"model"`
+	System     string               `json:"system,omitempty"`
+	Messages   []Message            `json:"messages"`
+	MaxTokens  int                  `json:"max_tokens"`
+	Tools      []AnthropicTool      `json:"tools,omitempty"`
+	ToolChoice *AnthropicToolChoice `json:"tool_choice,omitempty"`
+	Stream     bool                 `json:"stream,omitempty"`
+}
+
+// AnthropicTool declares a tool the model can call. Forcing a call to a tool
+// whose input schema is the expected output is how structured output is
+// requested from the Messages API.
+type AnthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema *JSONSchema `json:"input_schema"`
+}
+
+// AnthropicToolChoice makes the model call a specific tool
+type AnthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// AnthropicContentBlock is a block of a Messages API response
+type AnthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// AnthropicMessagesResponse represents a response from the Anthropic Messages API
+type AnthropicMessagesResponse struct {
+	Content    []AnthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicUsage is the token count of a Messages API response
+type AnthropicUsage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// AnthropicStreamEvent is an event of a streamed Messages API response
+type AnthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+	// Message is sent with message_start, including the input tokens
+	Message struct {
+		Usage AnthropicUsage `json:"usage"`
+	} `json:"message"`
+	// Usage is sent with message_delta, the output tokens so far
+	Usage AnthropicUsage `json:"usage"`
+}
+
+// NewAnthropicService creates a new Anthropic service. The API version
+// defaults to DefaultAnthropicVersion unless set in config.Headers.
+func NewAnthropicService(config LLMServiceConfig) (*AnthropicService, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("Anthropic API key is required")
+	}
+
+	retryConfig := DefaultRetryConfig()
+	if config.MaxRetries > 0 {
+		retryConfig.MaxRetries = config.MaxRetries
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	baseURL := DefaultAnthropicBaseURL
+	if config.BaseURL != "" {
+		baseURL = config.BaseURL
+	}
+
+	modelName := DefaultAnthropicModel
+	if config.ModelName != "" {
+		modelName = config.ModelName
+	}
+
+	headers := map[string]string{"anthropic-version": DefaultAnthropicVersion}
+	for name, value := range config.Headers {
+		headers[name] = value
+	}
+
+	baseClient := &BaseLLMClient{
+		APIKey:         config.APIKey,
+		APIEndpoint:    strings.TrimSuffix(baseURL, "/") + "/v1/messages",
+		ModelName:      modelName,
+		Provider:       string(ProviderAnthropic),
+		AuthScheme:     AuthHeader,
+		AuthHeaderName: "x-api-key",
+		Headers:        headers,
+		HTTPClient:     NewHTTPClient(timeout),
+		// Streams are bounded by the caller's context instead
+		StreamHTTPClient: NewHTTPClient(0),
+		Metrics:          NewLLMMetrics(),
+		RetryConfig:      retryConfig,
+		Prompts:          promptRegistry(config),
+		Price:            modelPrice(config, string(ProviderAnthropic), modelName),
+	}
+
+	return &AnthropicService{
+		BaseClient: baseClient,
+		MaxTokens:  DefaultAnthropicMaxTokens,
+	}, nil
+}
+
+// Model implements ModelIdentifier
+func (s *AnthropicService) Model() string {
+	return s.BaseClient.Provider + "/" + s.BaseClient.ModelName
+}
+
+// ProcessText implements LLMService.ProcessText
+func (s *AnthropicService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	content, usage, err := s.complete(ctx, prompt.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content, usage, s.complete)
+}
+
+// ProcessTextStream implements LLMService.ProcessTextStream. A repair of
+// invalid output isn't streamed.
+func (s *AnthropicService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+	request := s.request(prompt.Messages)
+	request.Stream = true
+
+	var content strings.Builder
+	var usage AnthropicUsage
+	err = s.BaseClient.SendStreamRequest(ctx, request, func(data []byte) error {
+		var event AnthropicStreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			usage = event.Message.Usage
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			// The forced tool call streams its input as JSON fragments
+			delta := event.Delta.PartialJSON
+			if event.Delta.Type == "text_delta" {
+				delta = event.Delta.Text
+			}
+			if delta != "" {
+				content.WriteString(delta)
+				if onDelta != nil {
+					onDelta(delta)
+				}
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			return anthropicStreamError(s.BaseClient.Provider, event.Error.Type, event.Error.Message)
+		}
+		return nil
+	})
+	recorded := s.BaseClient.recordUsage(usage.InputTokens, usage.OutputTokens)
+	if err != nil {
+		return nil, withUsage(err, recorded)
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content.String(), recorded, s.complete)
+}
+
+// complete sends a conversation and returns the input of the model's tool
+// call, or its text if it answered without calling the tool
+func (s *AnthropicService) complete(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+	var response AnthropicMessagesResponse
+	if err := s.BaseClient.SendRequest(ctx, s.request(messages), &response); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+	usage := s.BaseClient.recordUsage(response.Usage.InputTokens, response.Usage.OutputTokens)
+
+	var content strings.Builder
+	for _, block := range response.Content {
+		switch block.Type {
+		case "tool_use":
+			return string(block.Input), usage, nil
+		case "text":
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return "", models.TokenUsage{}, withUsage(ErrInvalidResponse, usage)
+	}
+
+	return content.String(), usage, nil
+}
+
+// request builds a Messages API request that forces the model to answer
+// through the analysis tool. The system message is moved to its own field.
+func (s *AnthropicService) request(messages []Message) AnthropicMessagesRequest {
+	request := AnthropicMessagesRequest{
+		Model:     s.BaseClient.ModelName,
+		MaxTokens: s.MaxTokens,
+		Tools: []AnthropicTool{{
+			Name:        ProcessedContentSchemaName,
+			Description: "Record the analysis of the learner's text",
+			InputSchema: ProcessedContentSchema,
+		}},
+		ToolChoice: &AnthropicToolChoice{Type: "tool", Name: ProcessedContentSchemaName},
+	}
+
+	for _, message := range messages {
+		if message.Role == "system" {
+			request.System = message.Content
+			continue
+		}
+		request.Messages = append(request.Messages, message)
+	}
+
+	return request
+}
+
+// anthropicStreamError converts an error event sent after the stream started
+// to the LLMError the same error would have produced as a response
+func anthropicStreamError(provider, errorType, message string) *LLMError {
+	switch errorType {
+	case "overloaded_error":
+		return NewLLMError(529, message, provider, true)
+	case "api_error":
+		return NewLLMError(http.StatusInternalServerError, message, provider, true)
+	case "rate_limit_error":
+		return NewLLMError(http.StatusTooManyRequests, message, provider, true)
+	default:
+		return NewLLMError(http.StatusBadRequest, message, provider, false)
+	}
+}
@@ -0,0 +1,310 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// testAnalysis is a model output that passes schema validation
+const testAnalysis = `{"content":"A friendly greeting.","tags":["greetings"],"analysis":{"vocabulary":[{"term":"Hola","lemma":"hola","partOfSpeech":"interjection","translation":"hello"}],"grammar":[],"culturalContext":"","alternatives":[],"mistakes":[]}}`
+
+// newTestAnthropicService creates an Anthropic service talking to a test server, without retries
+func newTestAnthropicService(t *testing.T, baseURL string, headers map[string]string) *AnthropicService {
+	t.Helper()
+	service, err := NewAnthropicService(LLMServiceConfig{
+		APIKey:       "test-key",
+		ModelName:    "claude-test",
+		ProviderType: ProviderAnthropic,
+		BaseURL:      baseURL,
+		Headers:      headers,
+		Prices:       map[string]ModelPrice{"anthropic/claude-test": {InputPerMillion: 3, OutputPerMillion: 15}},
+	})
+	if err != nil {
+		t.Fatalf("NewAnthropicService: %v", err)
+	}
+	service.BaseClient.RetryConfig = RetryConfig{}
+	return service
+}
+
+func TestAnthropicProcessText(t *testing.T) {
+	var request AnthropicMessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got != DefaultAnthropicVersion {
+			t.Errorf("anthropic-version = %q, want %s", got, DefaultAnthropicVersion)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("unexpected Authorization header %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		fmt.Fprintf(w, `{
+			"content": [
+				{"type": "text", "text": "Recording the analysis."},
+				{"type": "tool_use", "id": "toolu_1", "name": %q, "input": %s}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 1200, "output_tokens": 300}
+		}`, ProcessedContentSchemaName, testAnalysis)
+	}))
+	defer server.Close()
+
+	service := newTestAnthropicService(t, server.URL, nil)
+	result, err := service.ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if err != nil {
+		t.Fatalf("ProcessText: %v", err)
+	}
+
+	// The system prompt goes in the top-level field, not in the messages
+	if request.System == "" {
+		t.Error("expected the system prompt in the top-level system field")
+	}
+	if len(request.Messages) == 0 {
+		t.Fatal("expected at least one message")
+	}
+	for _, message := range request.Messages {
+		if message.Role == "system" {
+			t.Error("the system prompt must not be sent as a message")
+		}
+	}
+	if request.Model != "claude-test" || request.MaxTokens != DefaultAnthropicMaxTokens {
+		t.Errorf("got model %q with max_tokens %d", request.Model, request.MaxTokens)
+	}
+	if request.ToolChoice == nil || request.ToolChoice.Name != ProcessedContentSchemaName {
+		t.Errorf("expected a forced call of the %s tool, got %+v", ProcessedContentSchemaName, request.ToolChoice)
+	}
+
+	// The tool input is the result, the text block is ignored
+	if result.Content != "A friendly greeting." || len(result.Tags) != 1 || result.Tags[0] != "greetings" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Analysis == nil || len(result.Analysis.Vocabulary) != 1 || result.Analysis.Vocabulary[0].Lemma != "hola" {
+		t.Errorf("unexpected analysis %+v", result.Analysis)
+	}
+	if result.Provider != string(ProviderAnthropic) {
+		t.Errorf("Provider = %q", result.Provider)
+	}
+
+	usage := result.Usage
+	if usage.Model != "anthropic/claude-test" || usage.PromptTokens != 1200 || usage.CompletionTokens != 300 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+	if want := 1200*3/1e6 + 300*15/1e6; usage.CostUSD < want-1e-9 || usage.CostUSD > want+1e-9 {
+		t.Errorf("CostUSD = %f, want %f", usage.CostUSD, want)
+	}
+}
+
+func TestAnthropicProcessTextJoinsTextBlocks(t *testing.T) {
+	half := len(testAnalysis) / 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(AnthropicMessagesResponse{
+			Content: []AnthropicContentBlock{
+				{Type: "text", Text: testAnalysis[:half]},
+				{Type: "text", Text: testAnalysis[half:]},
+			},
+		})
+	}))
+	defer server.Close()
+
+	result, err := newTestAnthropicService(t, server.URL, nil).ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if err != nil {
+		t.Fatalf("ProcessText: %v", err)
+	}
+	if result.Content != "A friendly greeting." {
+		t.Errorf("Content = %q", result.Content)
+	}
+}
+
+func TestAnthropicProcessTextWithoutContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content": [], "stop_reason": "end_turn"}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestAnthropicService(t, server.URL, nil).ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if !errors.Is(err, ErrInvalidResponse) {
+		t.Fatalf("ProcessText = %v, want ErrInvalidResponse", err)
+	}
+}
+
+func TestAnthropicVersionHeaderOverride(t *testing.T) {
+	var version atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version.Store(r.Header.Get("anthropic-version"))
+		fmt.Fprintf(w, `{"content": [{"type": "tool_use", "input": %s}]}`, testAnalysis)
+	}))
+	defer server.Close()
+
+	service := newTestAnthropicService(t, server.URL, map[string]string{"anthropic-version": "2099-01-01"})
+	if _, err := service.ProcessText(context.Background(), "Hola", "English", "Spanish"); err != nil {
+		t.Fatalf("ProcessText: %v", err)
+	}
+	if got := version.Load(); got != "2099-01-01" {
+		t.Errorf("anthropic-version = %v, want 2099-01-01", got)
+	}
+}
+
+func TestAnthropicErrorStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		errorType string
+		retryable bool
+	}{
+		{http.StatusBadRequest, "invalid_request_error", false},
+		{http.StatusUnauthorized, "authentication_error", false},
+		{http.StatusForbidden, "permission_error", false},
+		{http.StatusNotFound, "not_found_error", false},
+		{http.StatusRequestEntityTooLarge, "request_too_large", false},
+		{http.StatusTooManyRequests, "rate_limit_error", true},
+		{http.StatusInternalServerError, "api_error", true},
+		{529, "overloaded_error", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.errorType, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"type": "error", "error": {"type": %q, "message": "something about %s"}}`, tt.errorType, tt.errorType)
+			}))
+			defer server.Close()
+
+			_, err := newTestAnthropicService(t, server.URL, nil).ProcessText(context.Background(), "Hola", "English", "Spanish")
+
+			var llmErr *LLMError
+			if !errors.As(err, &llmErr) {
+				t.Fatalf("ProcessText = %v, want an *LLMError", err)
+			}
+			if llmErr.StatusCode != tt.status || llmErr.Retryable != tt.retryable || llmErr.Provider != string(ProviderAnthropic) {
+				t.Errorf("got %+v, want status %d retryable %v", llmErr, tt.status, tt.retryable)
+			}
+			if llmErr.Message != "something about "+tt.errorType {
+				t.Errorf("Message = %q", llmErr.Message)
+			}
+			if n := requests.Load(); n != 1 {
+				t.Errorf("sent %d requests with retries disabled", n)
+			}
+		})
+	}
+}
+
+// writeEvents writes server-sent events as the Messages API streams them
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		var typed struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(event), &typed)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+	}
+}
+
+func TestAnthropicProcessTextStream(t *testing.T) {
+	var request AnthropicMessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", got)
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+
+		half := len(testAnalysis) / 2
+		first, _ := json.Marshal(testAnalysis[:half])
+		second, _ := json.Marshal(testAnalysis[half:])
+		writeEvents(w,
+			`{"type": "message_start", "message": {"usage": {"input_tokens": 800, "output_tokens": 1}}}`,
+			`{"type": "content_block_start", "index": 0, "content_block": {"type": "tool_use", "input": {}}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": `+string(first)+`}}`,
+			`{"type": "content_block_delta", "index": 0, "delta": {"type": "input_json_delta", "partial_json": `+string(second)+`}}`,
+			`{"type": "content_block_stop", "index": 0}`,
+			`{"type": "message_delta", "delta": {"stop_reason": "tool_use"}, "usage": {"output_tokens": 120}}`,
+			`{"type": "message_stop"}`,
+		)
+	}))
+	defer server.Close()
+
+	var deltas strings.Builder
+	service := newTestAnthropicService(t, server.URL, nil)
+	result, err := service.ProcessTextStream(context.Background(), "Hola", "English", "Spanish", func(delta string) {
+		deltas.WriteString(delta)
+	})
+	if err != nil {
+		t.Fatalf("ProcessTextStream: %v", err)
+	}
+
+	if !request.Stream || request.System == "" {
+		t.Errorf("expected a streaming request with a system prompt, got stream=%v system=%q", request.Stream, request.System)
+	}
+	if deltas.String() != testAnalysis {
+		t.Errorf("deltas = %q, want the tool input", deltas.String())
+	}
+	if result.Content != "A friendly greeting." {
+		t.Errorf("Content = %q", result.Content)
+	}
+	if result.Usage.PromptTokens != 800 || result.Usage.CompletionTokens != 120 {
+		t.Errorf("unexpected usage %+v", result.Usage)
+	}
+}
+
+func TestAnthropicStreamErrorEvent(t *testing.T) {
+	tests := []struct {
+		errorType string
+		status    int
+		retryable bool
+	}{
+		{"overloaded_error", 529, true},
+		{"api_error", http.StatusInternalServerError, true},
+		{"rate_limit_error", http.StatusTooManyRequests, true},
+		{"invalid_request_error", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.errorType, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeEvents(w,
+					`{"type": "message_start", "message": {"usage": {"input_tokens": 10}}}`,
+					fmt.Sprintf(`{"type": "error", "error": {"type": %q, "message": "stream broke"}}`, tt.errorType),
+				)
+			}))
+			defer server.Close()
+
+			_, err := newTestAnthropicService(t, server.URL, nil).ProcessTextStream(context.Background(), "Hola", "English", "Spanish", nil)
+
+			var llmErr *LLMError
+			if !errors.As(err, &llmErr) {
+				t.Fatalf("ProcessTextStream = %v, want an *LLMError", err)
+			}
+			if llmErr.StatusCode != tt.status || llmErr.Retryable != tt.retryable || llmErr.Message != "stream broke" {
+				t.Errorf("got %+v, want status %d retryable %v", llmErr, tt.status, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(529)
+		fmt.Fprint(w, `{"type": "error", "error": {"type": "overloaded_error", "message": "Overloaded"}}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestAnthropicService(t, server.URL, nil).ProcessTextStream(context.Background(), "Hola", "English", "Spanish", nil)
+
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) || llmErr.StatusCode != 529 || !llmErr.Retryable || llmErr.Message != "Overloaded" {
+		t.Fatalf("ProcessTextStream = %v, want a retryable 529 LLMError", err)
+	}
+}
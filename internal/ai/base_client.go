@@ -1,12 +1,18 @@
 package ai
 
 import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/prompts"
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -16,11 +22,26 @@ import (
 type BaseLLMClient struct {
 	APIKey      string
 	APIEndpoint string
-	ModelName   string
-	Provider    string
-	HTTPClient  HTTPClient
-	Metrics     *LLMMetrics
-	RetryConfig RetryConfig
+	// StreamEndpoint receives streaming requests, defaults to APIEndpoint
+	StreamEndpoint string
+	ModelName      string
+	Provider       string
+	// AuthScheme and AuthHeaderName control how APIKey is sent, by default
+	// as a bearer token
+	AuthScheme     AuthScheme
+	AuthHeaderName string
+	// Headers are added to every request
+	Headers    map[string]string
+	HTTPClient HTTPClient
+	// StreamHTTPClient sends streaming requests, which may legitimately
+	// outlast HTTPClient's timeout. Defaults to HTTPClient.
+	StreamHTTPClient HTTPClient
+	Metrics          *LLMMetrics
+	RetryConfig      RetryConfig
+	// Prompts renders the prompts sent to the provider
+	Prompts *prompts.Registry
+	// Price is what the model costs, nil if unknown
+	Price *ModelPrice
 }
 
 // HTTPClient interface abstracts the HTTP client
@@ -48,6 +69,9 @@ func DefaultRetryConfig() RetryConfig {
 type LLMMetrics struct {
 	RequestDuration *prometheus.HistogramVec
 	RequestCounter  *prometheus.CounterVec
+	RepairCounter   *prometheus.CounterVec
+	TokenCounter    *prometheus.CounterVec
+	CostCounter     *prometheus.CounterVec
 }
 
 // NewHTTPClient creates a HTTP client with proper timeouts
@@ -69,6 +93,27 @@ type Message struct {
 	Content string `json:"content"`
 }
 
+// ChatCompletionChunk is a single event of a streamed chat completion in the
+// OpenAI-compatible format
+type ChatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	// Usage is only set on the last chunk, and only if requested
+	Usage *ChatCompletionUsage `json:"usage"`
+}
+
+// ChatCompletionUsage is the token count of an OpenAI-compatible chat completion
+type ChatCompletionUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+// streamDone is the data of the event that ends an OpenAI-compatible stream
+const streamDone = "[DONE]"
+
 // SendRequest handles HTTP requests with metrics and retry logic
 func (c *BaseLLMClient) SendRequest(ctx context.Context, requestBody interface{}, responseObj interface{}) error {
 	var err error
@@ -110,7 +155,7 @@ func (c *BaseLLMClient) doSendRequest(ctx context.Context, requestBody interface
 
 	// Set common headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	c.setHeaders(req)
 
 	// Send the request
 	resp, err := c.HTTPClient.Do(req)
@@ -119,7 +164,7 @@ func (c *BaseLLMClient) doSendRequest(ctx context.Context, requestBody interface
 		if ctx.Err() == context.DeadlineExceeded {
 			return NewLLMError(http.StatusRequestTimeout, "request timed out", c.Provider, true)
 		}
-		return fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", c.redact(err))
 	}
 	defer resp.Body.Close()
 
@@ -145,6 +190,241 @@ func (c *BaseLLMClient) doSendRequest(ctx context.Context, requestBody interface
 	return nil
 }
 
+// SendStreamRequest sends a request with streaming enabled and calls onEvent
+// with the data of every server-sent event until the stream ends. Only
+// opening the stream is retried, a stream that breaks off midway fails.
+func (c *BaseLLMClient) SendStreamRequest(ctx context.Context, requestBody interface{}, onEvent func(data []byte) error) error {
+	start := time.Now()
+	status := "success"
+
+	var resp *http.Response
+	err := withRetry(ctx, c.RetryConfig, func() error {
+		var err error
+		resp, err = c.openStream(ctx, requestBody)
+		return err
+	})
+	if err == nil {
+		err = readEventStream(resp.Body, onEvent)
+		resp.Body.Close()
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = NewLLMError(http.StatusRequestTimeout, "stream timed out", c.Provider, true)
+		}
+	}
+
+	// Record metrics
+	if err != nil {
+		status = "error"
+	}
+	if c.Metrics != nil {
+		duration := time.Since(start).Seconds()
+		c.Metrics.RequestDuration.WithLabelValues(c.Provider, status).Observe(duration)
+		c.Metrics.RequestCounter.WithLabelValues(c.Provider, status).Inc()
+	}
+
+	return err
+}
+
+// StreamChatCompletion streams an OpenAI-compatible chat completion, passing
+// every content delta to onDelta, and returns the complete content and its
+// usage, which is zero if the server didn't report it. Errors carry the
+// usage reported before the stream failed.
+func (c *BaseLLMClient) StreamChatCompletion(ctx context.Context, requestBody interface{}, onDelta func(string)) (string, models.TokenUsage, error) {
+	var content strings.Builder
+	var usage ChatCompletionUsage
+	err := c.SendStreamRequest(ctx, requestBody, func(data []byte) error {
+		if string(data) == streamDone {
+			return errStreamDone
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		return nil
+	})
+	recorded := c.recordUsage(usage.PromptTokens, usage.CompletionTokens)
+	if err != nil {
+		return "", models.TokenUsage{}, withUsage(err, recorded)
+	}
+	if content.Len() == 0 {
+		return "", models.TokenUsage{}, withUsage(ErrInvalidResponse, recorded)
+	}
+
+	return content.String(), recorded, nil
+}
+
+// setHeaders adds the credentials and the configured extra headers to a request
+func (c *BaseLLMClient) setHeaders(req *http.Request) {
+	switch c.AuthScheme {
+	case AuthNone:
+	case AuthHeader:
+		req.Header.Set(c.AuthHeaderName, c.APIKey)
+	case AuthQuery:
+		query := req.URL.Query()
+		query.Set(c.AuthHeaderName, c.APIKey)
+		req.URL.RawQuery = query.Encode()
+	default:
+		if c.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.APIKey)
+		}
+	}
+
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// redact removes the API key from errors that quote the request URL
+func (c *BaseLLMClient) redact(err error) error {
+	var urlErr *url.Error
+	if c.AuthScheme == AuthQuery && c.APIKey != "" && errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, url.QueryEscape(c.APIKey), "REDACTED")
+	}
+	return err
+}
+
+// recordRepair counts the outcome of a repair re-prompt
+func (c *BaseLLMClient) recordRepair(result string) {
+	if c.Metrics != nil {
+		c.Metrics.RepairCounter.WithLabelValues(c.Provider, result).Inc()
+	}
+}
+
+// recordUsage prices the tokens of a call and counts them
+func (c *BaseLLMClient) recordUsage(promptTokens, completionTokens int64) models.TokenUsage {
+	usage := models.TokenUsage{
+		Model:            c.Provider + "/" + c.ModelName,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}
+	if c.Price != nil {
+		usage.CostUSD = c.Price.Cost(promptTokens, completionTokens)
+	}
+
+	if c.Metrics != nil {
+		c.Metrics.TokenCounter.WithLabelValues(c.Provider, c.ModelName, "prompt").Add(float64(promptTokens))
+		c.Metrics.TokenCounter.WithLabelValues(c.Provider, c.ModelName, "completion").Add(float64(completionTokens))
+		c.Metrics.CostCounter.WithLabelValues(c.Provider, c.ModelName).Add(usage.CostUSD)
+	}
+	return usage
+}
+
+// openStream sends a streaming request and returns the response once the
+// provider accepted it
+func (c *BaseLLMClient) openStream(ctx context.Context, requestBody interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := c.StreamEndpoint
+	if endpoint == "" {
+		endpoint = c.APIEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	c.setHeaders(req)
+
+	httpClient := c.StreamHTTPClient
+	if httpClient == nil {
+		httpClient = c.HTTPClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, NewLLMError(http.StatusRequestTimeout, "request timed out", c.Provider, true)
+		}
+		return nil, fmt.Errorf("failed to send request: %w", c.redact(err))
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		errorMsg := extractErrorMessage(bodyBytes)
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == 429
+
+		return nil, NewLLMError(resp.StatusCode, errorMsg, c.Provider, retryable)
+	}
+
+	return resp, nil
+}
+
+// errStreamDone is returned by event handlers to end a stream early
+var errStreamDone = errors.New("stream done")
+
+// readEventStream parses a text/event-stream body and calls onEvent with the
+// data of each event. Multi-line data is joined with newlines, comments and
+// other fields are ignored.
+func readEventStream(body io.Reader, onEvent func(data []byte) error) error {
+	reader := bufio.NewReader(body)
+	var data []byte
+	hasData := false
+
+	dispatch := func() error {
+		if !hasData {
+			return nil
+		}
+		err := onEvent(data)
+		data = data[:0]
+		hasData = false
+		return err
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if errors.Is(err, errStreamDone) {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			value := strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			if hasData {
+				data = append(data, '\n')
+			}
+			data = append(data, value...)
+			hasData = true
+		}
+
+		if readErr == io.EOF {
+			// The final event may lack its terminating blank line
+			if err := dispatch(); err != nil && !errors.Is(err, errStreamDone) {
+				return err
+			}
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read stream: %w", readErr)
+		}
+	}
+}
+
 // withRetry implements retry logic with exponential backoff
 func withRetry(ctx context.Context, config RetryConfig, fn func() error) error {
 	var err error
@@ -186,12 +466,21 @@ func withRetry(ctx context.Context, config RetryConfig, fn func() error) error {
 func extractErrorMessage(bodyBytes []byte) string {
 	var errorResponse map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &errorResponse); err != nil {
-		return "unknown error occurred"
+		// Some APIs (e.g. Gemini's streaming endpoint) wrap the error in an array
+		var errorList []map[string]interface{}
+		if err := json.Unmarshal(bodyBytes, &errorList); err != nil || len(errorList) == 0 {
+			return "unknown error occurred"
+		}
+		errorResponse = errorList[0]
 	}
 
 	// Try various common error message formats
 	if errObj, ok := errorResponse["error"].(map[string]interface{}); ok {
 		if msg, ok := errObj["message"].(string); ok {
+			// Google APIs add a canonical status such as INVALID_ARGUMENT
+			if status, ok := errObj["status"].(string); ok && status != "" {
+				return status + ": " + msg
+			}
 			return msg
 		}
 	}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/prompts"
 	"context"
 )
 
@@ -9,14 +10,34 @@ import (
 type LLMService interface {
 	// ProcessText processes text input and returns structured content
 	ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error)
+	// ProcessTextStream is like ProcessText but passes the completion to
+	// onDelta piece by piece as the provider generates it
+	ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error)
 }
 
 // ProviderType represents the type of LLM provider
 type ProviderType string
 
 const (
-	ProviderOpenAI   ProviderType = "openai"
-	ProviderDeepseek ProviderType = "deepseek"
+	ProviderOpenAI           ProviderType = "openai"
+	ProviderDeepseek         ProviderType = "deepseek"
+	ProviderOpenAICompatible ProviderType = "openai-compatible"
+	ProviderAnthropic        ProviderType = "anthropic"
+	ProviderGemini           ProviderType = "gemini"
+)
+
+// AuthScheme is how the API key is sent to a provider
+type AuthScheme string
+
+const (
+	// AuthBearer sends "Authorization: Bearer <key>", the default
+	AuthBearer AuthScheme = "bearer"
+	// AuthHeader sends the bare key in the header named by AuthHeaderName
+	AuthHeader AuthScheme = "header"
+	// AuthQuery sends the key in the query parameter named by AuthHeaderName
+	AuthQuery AuthScheme = "query"
+	// AuthNone sends no credentials
+	AuthNone AuthScheme = "none"
 )
 
 // LLMServiceConfig contains configuration for LLM services
@@ -26,4 +47,19 @@ type LLMServiceConfig struct {
 	MaxRetries   int
 	Timeout      int // in seconds
 	ProviderType ProviderType
+
+	// BaseURL overrides the provider's API root, e.g. http://localhost:8000/v1
+	BaseURL string
+	// AuthScheme and AuthHeaderName control how APIKey is sent
+	AuthScheme     AuthScheme
+	AuthHeaderName string
+	// Headers are added to every request
+	Headers map[string]string
+	// ResponseFormat overrides how OpenAI-compatible APIs are asked for
+	// structured output
+	ResponseFormat ResponseFormatType
+	// Prompts renders the prompts, defaulting to the embedded templates
+	Prompts *prompts.Registry
+	// Prices override DefaultPrices, see ParsePrices
+	Prices map[string]ModelPrice
 }
@@ -0,0 +1,244 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/prompts"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// CacheKeyPrefix prefixes the Redis keys of cached LLM responses
+const CacheKeyPrefix = "llm:cache:"
+
+// DefaultCacheTTL is how long responses are cached if no TTL is configured
+const DefaultCacheTTL = 7 * 24 * time.Hour
+
+// cacheBypassKey marks contexts whose requests skip the cache lookup
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context whose LLM requests aren't answered from the
+// cache. Their fresh responses still replace the cached ones.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether the context skips the cache lookup
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// ModelIdentifier is implemented by services that can name the model they use
+type ModelIdentifier interface {
+	// Model returns "<provider>/<model>"
+	Model() string
+}
+
+// CacheMetrics holds Prometheus metrics for the LLM response cache
+type CacheMetrics struct {
+	Hits   prometheus.Counter
+	Misses prometheus.Counter
+}
+
+// NewCacheMetrics creates and registers metrics for the LLM response cache
+func NewCacheMetrics() *CacheMetrics {
+	hits := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "llm_cache_hits_total",
+			Help: "Total number of LLM requests answered from the cache",
+		},
+	)
+
+	misses := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "llm_cache_misses_total",
+			Help: "Total number of LLM requests not found in the cache",
+		},
+	)
+
+	// Register metrics with Prometheus
+	prometheus.MustRegister(hits, misses)
+
+	return &CacheMetrics{
+		Hits:   hits,
+		Misses: misses,
+	}
+}
+
+// cachedOutput is the part of a cached response the model generated, without
+// what the services add to it
+type cachedOutput struct {
+	Content  string               `json:"content"`
+	Tags     []string             `json:"tags"`
+	Analysis *models.NoteAnalysis `json:"analysis"`
+}
+
+// CachedLLMService decorates an LLMService with a Redis cache of its
+// responses, so identical texts are only sent to the provider once
+type CachedLLMService struct {
+	next    LLMService
+	client  *redis.Client
+	ttl     time.Duration
+	model   string
+	prompts *prompts.Registry
+	metrics *CacheMetrics
+}
+
+// NewCachedLLMService creates a caching decorator around an LLM service.
+// Responses are cached per version of the prompt templates in the registry,
+// which defaults to the embedded templates.
+func NewCachedLLMService(next LLMService, client *redis.Client, ttl time.Duration, registry *prompts.Registry) *CachedLLMService {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	if registry == nil {
+		registry = prompts.Embedded()
+	}
+
+	model := "unknown"
+	if identifier, ok := next.(ModelIdentifier); ok {
+		model = identifier.Model()
+	}
+
+	return &CachedLLMService{
+		next:    next,
+		client:  client,
+		ttl:     ttl,
+		model:   model,
+		prompts: registry,
+		metrics: NewCacheMetrics(),
+	}
+}
+
+// Model implements ModelIdentifier
+func (s *CachedLLMService) Model() string {
+	return s.model
+}
+
+// ProcessText implements LLMService.ProcessText
+func (s *CachedLLMService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	key := s.cacheKey(text, sourceLanguage, targetLanguage)
+	if content := s.lookup(ctx, key); content != nil {
+		return content, nil
+	}
+
+	content, err := s.next.ProcessText(ctx, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	s.store(ctx, key, content)
+	return content, nil
+}
+
+// ProcessTextStream implements LLMService.ProcessTextStream. A cached
+// response is passed to onDelta in one piece, shaped like the model's output
+// a live stream delivers.
+func (s *CachedLLMService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	key := s.cacheKey(text, sourceLanguage, targetLanguage)
+	if content := s.lookup(ctx, key); content != nil {
+		if onDelta != nil {
+			if raw, err := json.Marshal(cachedOutput{Content: content.Content, Tags: content.Tags, Analysis: content.Analysis}); err == nil {
+				onDelta(string(raw))
+			}
+		}
+		return content, nil
+	}
+
+	content, err := s.next.ProcessTextStream(ctx, text, sourceLanguage, targetLanguage, onDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	s.store(ctx, key, content)
+	return content, nil
+}
+
+// lookup returns the cached response for a key, or nil on a miss. Cache
+// failures are logged and treated as misses.
+func (s *CachedLLMService) lookup(ctx context.Context, key string) *dto.ProcessedContent {
+	if key == "" || CacheBypassed(ctx) {
+		return nil
+	}
+
+	raw, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			log.Printf("Failed to read LLM cache: %v", err)
+		}
+		s.metrics.Misses.Inc()
+		return nil
+	}
+
+	var content dto.ProcessedContent
+	if err := json.Unmarshal(raw, &content); err != nil || content.Content == "" {
+		s.metrics.Misses.Inc()
+		return nil
+	}
+
+	// A hit costs nothing, whatever the original response did
+	content.Usage = models.TokenUsage{}
+
+	s.metrics.Hits.Inc()
+	return &content
+}
+
+// store caches a response. Failures are only logged.
+func (s *CachedLLMService) store(ctx context.Context, key string, content *dto.ProcessedContent) {
+	if key == "" {
+		return
+	}
+
+	raw, err := json.Marshal(content)
+	if err != nil {
+		log.Printf("Failed to marshal LLM response for the cache: %v", err)
+		return
+	}
+
+	// Cache even if the caller gave up in the meantime
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := s.client.Set(ctx, key, raw, s.ttl).Err(); err != nil {
+		log.Printf("Failed to write LLM cache: %v", err)
+	}
+}
+
+// cacheKey hashes everything the response depends on. It returns "" if the
+// prompt templates can't be resolved, which disables caching of the request.
+func (s *CachedLLMService) cacheKey(text, sourceLanguage, targetLanguage string) string {
+	fingerprint, err := s.prompts.Fingerprint(targetLanguage)
+	if err != nil {
+		log.Printf("Not caching LLM response: %v", err)
+		return ""
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{
+		normalizeText(text),
+		strings.ToLower(strings.TrimSpace(sourceLanguage)),
+		strings.ToLower(strings.TrimSpace(targetLanguage)),
+		s.model,
+		fingerprint,
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return CacheKeyPrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizeText trims the text and collapses runs of whitespace. Case is
+// kept since it can matter to the analysis.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
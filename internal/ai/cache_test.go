@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamingService is an LLMService streaming testAnalysis in two deltas
+type streamingService struct {
+	calls int
+}
+
+func (s *streamingService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	return s.ProcessTextStream(ctx, text, sourceLanguage, targetLanguage, nil)
+}
+
+func (s *streamingService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	s.calls++
+	if onDelta != nil {
+		half := len(testAnalysis) / 2
+		onDelta(testAnalysis[:half])
+		onDelta(testAnalysis[half:])
+	}
+
+	content, err := ParseJSONContent(testAnalysis)
+	if err != nil {
+		return nil, err
+	}
+	content.Provider = "test"
+	content.PromptVersion = "analysis/v2"
+	content.Usage = testUsage(100, 20)
+	return content, nil
+}
+
+func TestCachedLLMServiceStream(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	next := &streamingService{}
+	// The cache registers its metrics, so it is only created once
+	service := NewCachedLLMService(next, client, time.Hour, nil)
+	ctx := context.Background()
+
+	stream := func() (string, *dto.ProcessedContent) {
+		t.Helper()
+		var streamed string
+		content, err := service.ProcessTextStream(ctx, "Hola", "English", "Spanish", func(delta string) {
+			streamed += delta
+		})
+		if err != nil {
+			t.Fatalf("ProcessTextStream: %v", err)
+		}
+		return streamed, content
+	}
+
+	// A miss streams from the provider and stores the response
+	missDeltas, missed := stream()
+	if next.calls != 1 || missDeltas != testAnalysis {
+		t.Fatalf("miss made %d calls and streamed %q, want 1 call streaming the model output", next.calls, missDeltas)
+	}
+	if missed.Usage != testUsage(100, 20) {
+		t.Fatalf("miss Usage = %+v, want the provider's", missed.Usage)
+	}
+
+	// A hit answers without the provider, streaming the same shape in one delta
+	hitDeltas, hit := stream()
+	if next.calls != 1 {
+		t.Fatalf("hit made %d provider calls, want none", next.calls-1)
+	}
+	var got, want map[string]interface{}
+	if err := json.Unmarshal([]byte(hitDeltas), &got); err != nil {
+		t.Fatalf("hit streamed invalid JSON %q: %v", hitDeltas, err)
+	}
+	if err := json.Unmarshal([]byte(testAnalysis), &want); err != nil {
+		t.Fatalf("invalid testAnalysis: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("hit streamed %s, want the model output %s", hitDeltas, testAnalysis)
+	}
+
+	if hit.Content != missed.Content || hit.Provider != "test" || hit.PromptVersion != "analysis/v2" {
+		t.Fatalf("hit = %+v, want the cached response", hit)
+	}
+	if hit.Usage != (models.TokenUsage{}) {
+		t.Fatalf("hit Usage = %+v, want none", hit.Usage)
+	}
+
+	// Bypassing the cache reaches the provider again
+	if _, err := service.ProcessTextStream(WithoutCache(ctx), "Hola", "English", "Spanish", nil); err != nil {
+		t.Fatalf("ProcessTextStream: %v", err)
+	}
+	if next.calls != 2 {
+		t.Fatalf("bypass made %d provider calls in total, want 2", next.calls)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a single probe request through
+	CircuitHalfOpen
+	// CircuitOpen rejects all requests until the open timeout has passed
+	CircuitOpen
+)
+
+// String returns the state's name
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig holds the tunables of a CircuitBreaker
+type CircuitBreakerConfig struct {
+	// FailureThreshold is how many consecutive failures open the circuit
+	FailureThreshold int
+	// OpenTimeout is how long an open circuit rejects requests before a
+	// probe request is let through
+	OpenTimeout time.Duration
+}
+
+// CircuitBreaker stops sending requests to a provider that keeps failing.
+// After OpenTimeout a single probe is let through: its success closes the
+// circuit again, its failure reopens it.
+type CircuitBreaker struct {
+	config   CircuitBreakerConfig
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	onChange func(CircuitState)
+}
+
+// NewCircuitBreaker creates a closed circuit breaker. onChange, if set, is
+// called with every new state.
+func NewCircuitBreaker(config CircuitBreakerConfig, onChange func(CircuitState)) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+
+	return &CircuitBreaker{
+		config:   config,
+		onChange: onChange,
+	}
+}
+
+// Allow reports whether a request may be sent. Every allowed request must be
+// followed by RecordSuccess, RecordFailure or RecordIgnored.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.config.OpenTimeout {
+			return false
+		}
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false // Another request is already probing
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the circuit
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.setState(CircuitClosed)
+}
+
+// RecordFailure counts a failure, opening the circuit once the threshold is
+// reached or if the failed request was a probe
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.probing = false
+		b.openedAt = time.Now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// RecordIgnored ends a request whose outcome says nothing about the
+// provider's health, e.g. one the caller cancelled
+func (b *CircuitBreaker) RecordIgnored() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns the current state
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// setState switches to a state and reports it. Must be called with mu held.
+func (b *CircuitBreaker) setState(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
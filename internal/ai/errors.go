@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"ai-language-notes/internal/models"
 	"errors"
 	"fmt"
 )
@@ -38,6 +39,47 @@ func NewLLMError(statusCode int, message, provider string, retryable bool) *LLME
 	}
 }
 
+// UsageError is returned when a request failed after providers had already
+// charged for tokens, e.g. output that stayed invalid after a repair
+type UsageError struct {
+	// Usage has one entry per model
+	Usage []models.TokenUsage
+	Err   error
+}
+
+// Error implements the error interface
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// withUsage attaches the usage spent so far to an error. usages must include
+// any usage already attached to err.
+func withUsage(err error, usages ...models.TokenUsage) error {
+	var merged []models.TokenUsage
+	for _, usage := range usages {
+		merged = models.AddUsage(merged, usage)
+	}
+	if len(merged) == 0 {
+		return err
+	}
+	return &UsageError{Usage: merged, Err: err}
+}
+
+// UsageOf returns the usage spent by a failed request per model, nil if
+// there was none
+func UsageOf(err error) []models.TokenUsage {
+	var usageErr *UsageError
+	if errors.As(err, &usageErr) {
+		return usageErr.Usage
+	}
+	return nil
+}
+
 // IsRetryableError checks if an error should be retried
 func IsRetryableError(err error) bool {
 	var llmErr *LLMError
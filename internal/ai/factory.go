@@ -1,49 +1,157 @@
 package ai
 
 import (
+	"ai-language-notes/internal/config"
+	"ai-language-notes/internal/prompts"
 	"fmt"
+	"strings"
 )
 
 // NewLLMService creates an LLM service based on the provided configuration
 func NewLLMService(config LLMServiceConfig) (LLMService, error) {
 	switch config.ProviderType {
-	case ProviderOpenAI:
-		if config.APIKey == "" {
-			return nil, fmt.Errorf("OpenAI API key is required")
-		}
-		return NewOpenAIService(config), nil
+	case ProviderOpenAI, ProviderDeepseek, ProviderOpenAICompatible:
+		return NewOpenAICompatibleService(config)
 
-	case ProviderDeepseek:
-		if config.APIKey == "" {
-			return nil, fmt.Errorf("Deepseek API key is required")
-		}
-		return NewDeepseekService(config), nil
+	case ProviderAnthropic:
+		return NewAnthropicService(config)
+
+	case ProviderGemini:
+		return NewGeminiService(config)
 
 	default:
 		return nil, fmt.Errorf("unsupported LLM provider: %s", config.ProviderType)
 	}
 }
 
+// promptRegistry returns the prompt registry of a provider's config,
+// defaulting to the embedded templates
+func promptRegistry(config LLMServiceConfig) *prompts.Registry {
+	if config.Prompts != nil {
+		return config.Prompts
+	}
+	return prompts.Embedded()
+}
+
 // CreateLLMServiceFromConfig creates an LLM service from provider name and API key
 // This is a convenience function for simpler configuration
 func CreateLLMServiceFromConfig(providerName string, apiKey string) (LLMService, error) {
-	var providerType ProviderType
+	config := LLMServiceConfig{
+		APIKey:       apiKey,
+		ProviderType: ProviderType(providerName),
+		MaxRetries:   3,
+		Timeout:      30,
+	}
 
-	switch providerName {
-	case "openai":
-		providerType = ProviderOpenAI
-	case "deepseek":
-		providerType = ProviderDeepseek
-	default:
-		return nil, fmt.Errorf("unsupported provider name: %s", providerName)
+	return NewLLMService(config)
+}
+
+// ProviderSpec configures a provider of a fallback chain
+type ProviderSpec struct {
+	Name   string
+	Config LLMServiceConfig
+}
+
+// CreateLLMServiceChain creates an LLM service that tries the given providers
+// in order, skipping providers whose circuit breaker is open. A single
+// provider is returned as is.
+func CreateLLMServiceChain(specs []ProviderSpec, breakerConfig CircuitBreakerConfig) (LLMService, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no LLM provider configured")
 	}
 
-	config := LLMServiceConfig{
-		APIKey:       apiKey,
+	names := make([]string, len(specs))
+	services := make([]LLMService, len(specs))
+	for i, spec := range specs {
+		service, err := NewLLMService(spec.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM provider %s: %w", spec.Name, err)
+		}
+		names[i] = spec.Name
+		services[i] = service
+	}
+
+	if len(services) == 1 {
+		return services[0], nil
+	}
+	return NewFallbackLLMService(names, services, breakerConfig), nil
+}
+
+// CreateLLMServiceFromAppConfig creates the LLM service described by the
+// application config: the provider chain with each provider's settings,
+// rendering prompts from the given registry
+func CreateLLMServiceFromAppConfig(cfg config.Config, registry *prompts.Registry) (LLMService, error) {
+	prices, err := ParsePrices(cfg.LLMPrices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_PRICES: %w", err)
+	}
+
+	var specs []ProviderSpec
+	for _, name := range cfg.LLMProviderChain() {
+		serviceConfig := providerConfig(cfg, ProviderType(name))
+		serviceConfig.Prompts = registry
+		serviceConfig.Prices = prices
+		specs = append(specs, ProviderSpec{
+			Name:   name,
+			Config: serviceConfig,
+		})
+	}
+
+	return CreateLLMServiceChain(specs, CircuitBreakerConfig{
+		FailureThreshold: cfg.LLMBreakerFailures,
+		OpenTimeout:      cfg.LLMBreakerOpenTimeout,
+	})
+}
+
+// providerConfig picks a provider's settings from the application config
+func providerConfig(cfg config.Config, providerType ProviderType) LLMServiceConfig {
+	serviceConfig := LLMServiceConfig{
 		ProviderType: providerType,
 		MaxRetries:   3,
 		Timeout:      30,
 	}
 
-	return NewLLMService(config)
+	switch providerType {
+	case ProviderOpenAI:
+		serviceConfig.APIKey = cfg.OpenAIAPIKey
+		serviceConfig.BaseURL = cfg.OpenAIBaseURL
+		serviceConfig.ModelName = cfg.OpenAIModel
+	case ProviderDeepseek:
+		serviceConfig.APIKey = cfg.DeepSeekAPIKey
+		serviceConfig.BaseURL = cfg.DeepSeekBaseURL
+		serviceConfig.ModelName = cfg.DeepSeekModel
+	case ProviderOpenAICompatible:
+		serviceConfig.APIKey = cfg.CompatAPIKey
+		serviceConfig.BaseURL = cfg.CompatBaseURL
+		serviceConfig.ModelName = cfg.CompatModel
+		serviceConfig.AuthScheme = AuthScheme(cfg.CompatAuthScheme)
+		serviceConfig.AuthHeaderName = cfg.CompatAuthHeader
+		serviceConfig.Headers = parseHeaders(cfg.CompatHeaders)
+		serviceConfig.ResponseFormat = ResponseFormatType(cfg.CompatResponseFormat)
+	case ProviderAnthropic:
+		serviceConfig.APIKey = cfg.AnthropicAPIKey
+		serviceConfig.BaseURL = cfg.AnthropicBaseURL
+		serviceConfig.ModelName = cfg.AnthropicModel
+		if cfg.AnthropicVersion != "" {
+			serviceConfig.Headers = map[string]string{"anthropic-version": cfg.AnthropicVersion}
+		}
+	case ProviderGemini:
+		serviceConfig.APIKey = cfg.GeminiAPIKey
+		serviceConfig.BaseURL = cfg.GeminiBaseURL
+		serviceConfig.ModelName = cfg.GeminiModel
+	}
+
+	return serviceConfig
+}
+
+// parseHeaders parses "Name: value" pairs separated by commas
+func parseHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(pair, ":")
+		if name = strings.TrimSpace(name); ok && name != "" {
+			headers[name] = strings.TrimSpace(value)
+		}
+	}
+	return headers
 }
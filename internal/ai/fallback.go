@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// FallbackMetrics holds Prometheus metrics for the provider fallback chain
+type FallbackMetrics struct {
+	Served       *prometheus.CounterVec
+	Fallbacks    *prometheus.CounterVec
+	CircuitState *prometheus.GaugeVec
+}
+
+// NewFallbackMetrics creates and registers metrics for the provider fallback chain
+func NewFallbackMetrics() *FallbackMetrics {
+	served := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_provider_served_total",
+			Help: "Total number of LLM requests served, by the provider that served them",
+		},
+		[]string{"provider"},
+	)
+
+	fallbacks := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_provider_fallbacks_total",
+			Help: "Total number of times a provider was skipped or failed and the next one was tried",
+		},
+		[]string{"provider", "reason"},
+	)
+
+	circuitState := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "llm_provider_circuit_state",
+			Help: "State of each provider's circuit breaker (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"provider"},
+	)
+
+	// Register metrics with Prometheus
+	prometheus.MustRegister(served, fallbacks, circuitState)
+
+	return &FallbackMetrics{
+		Served:       served,
+		Fallbacks:    fallbacks,
+		CircuitState: circuitState,
+	}
+}
+
+// fallbackProvider is a provider of a fallback chain with its circuit breaker
+type fallbackProvider struct {
+	name    string
+	service LLMService
+	breaker *CircuitBreaker
+}
+
+// FallbackLLMService tries an ordered list of providers until one succeeds.
+// Providers that keep failing are skipped for a while by their circuit breaker.
+type FallbackLLMService struct {
+	providers []*fallbackProvider
+	metrics   *FallbackMetrics
+}
+
+// NewFallbackLLMService creates a fallback chain from named services, tried in order
+func NewFallbackLLMService(names []string, services []LLMService, breakerConfig CircuitBreakerConfig) *FallbackLLMService {
+	metrics := NewFallbackMetrics()
+
+	providers := make([]*fallbackProvider, len(services))
+	for i, service := range services {
+		name := names[i]
+		providers[i] = &fallbackProvider{
+			name:    name,
+			service: service,
+			breaker: NewCircuitBreaker(breakerConfig, func(state CircuitState) {
+				log.Printf("LLM provider %s circuit is now %s", name, state)
+				metrics.CircuitState.WithLabelValues(name).Set(float64(state))
+			}),
+		}
+		metrics.CircuitState.WithLabelValues(name).Set(float64(CircuitClosed))
+	}
+
+	return &FallbackLLMService{
+		providers: providers,
+		metrics:   metrics,
+	}
+}
+
+// Model implements ModelIdentifier
+func (s *FallbackLLMService) Model() string {
+	models := make([]string, len(s.providers))
+	for i, provider := range s.providers {
+		models[i] = provider.name
+		if identifier, ok := provider.service.(ModelIdentifier); ok {
+			models[i] = identifier.Model()
+		}
+	}
+	return strings.Join(models, ",")
+}
+
+// ProcessText implements LLMService.ProcessText
+func (s *FallbackLLMService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	return s.try(ctx, func(service LLMService) (*dto.ProcessedContent, bool, error) {
+		content, err := service.ProcessText(ctx, text, sourceLanguage, targetLanguage)
+		return content, true, err
+	})
+}
+
+// ProcessTextStream implements LLMService.ProcessTextStream. Once a provider
+// has streamed part of its output it isn't replaced by the next one anymore.
+func (s *FallbackLLMService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	return s.try(ctx, func(service LLMService) (*dto.ProcessedContent, bool, error) {
+		streamed := false
+		content, err := service.ProcessTextStream(ctx, text, sourceLanguage, targetLanguage, func(delta string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+		return content, !streamed, err
+	})
+}
+
+// try calls the providers in order until one succeeds. call reports whether
+// a failed request may still be handed to the next provider. Usage spent by
+// providers that failed is kept per model, as the result's FailedUsage or
+// on the error.
+func (s *FallbackLLMService) try(ctx context.Context, call func(LLMService) (*dto.ProcessedContent, bool, error)) (*dto.ProcessedContent, error) {
+	var lastErr error
+	var spent []models.TokenUsage
+	for _, provider := range s.providers {
+		if !provider.breaker.Allow() {
+			s.metrics.Fallbacks.WithLabelValues(provider.name, "circuit_open").Inc()
+			continue
+		}
+
+		content, canFallBack, err := call(provider.service)
+		if err == nil {
+			provider.breaker.RecordSuccess()
+			s.metrics.Served.WithLabelValues(provider.name).Inc()
+			content.Provider = provider.name
+			for _, usage := range spent {
+				content.FailedUsage = models.AddUsage(content.FailedUsage, usage)
+			}
+			return content, nil
+		}
+		for _, usage := range UsageOf(err) {
+			spent = models.AddUsage(spent, usage)
+		}
+
+		if ctx.Err() != nil {
+			// The caller gave up, that says nothing about the provider
+			provider.breaker.RecordIgnored()
+			return nil, withUsage(err, spent...)
+		}
+
+		if !isProviderFailure(err) {
+			// The request itself was rejected, other providers would too
+			provider.breaker.RecordIgnored()
+			return nil, withUsage(err, spent...)
+		}
+
+		provider.breaker.RecordFailure()
+		log.Printf("LLM provider %s failed: %v", provider.name, err)
+		lastErr = err
+		if !canFallBack {
+			return nil, withUsage(err, spent...)
+		}
+		s.metrics.Fallbacks.WithLabelValues(provider.name, "error").Inc()
+	}
+
+	if lastErr != nil {
+		return nil, withUsage(fmt.Errorf("all LLM providers failed: %w", lastErr), spent...)
+	}
+	return nil, NewLLMError(http.StatusServiceUnavailable, "all LLM providers are unavailable", "fallback", true)
+}
+
+// isProviderFailure reports whether an error points at the provider rather
+// than the request: server errors, rate limits, timeouts, rejected
+// credentials, unknown models, network errors and unusable responses
+func isProviderFailure(err error) bool {
+	var llmErr *LLMError
+	if !errors.As(err, &llmErr) {
+		return true
+	}
+	if llmErr.Retryable {
+		return true
+	}
+	switch llmErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusPaymentRequired:
+		return true
+	}
+	return false
+}
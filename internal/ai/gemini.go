@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Gemini API defaults
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-1.5-flash"
+)
+
+// GeminiService implements LLMService using Google's Gemini generateContent API
+type GeminiService struct {
+	BaseClient *BaseLLMClient
+}
+
+// GeminiPart is a piece of content, here always text
+type GeminiPart struct {
+	Text string `json:"text"`
+}
+
+// GeminiContent is a turn of the conversation
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiGenerationConfig controls the generated output
+type GeminiGenerationConfig struct {
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
+}
+
+// GeminiGenerateContentRequest represents a request to the Gemini generateContent API
+type GeminiGenerateContentRequest struct {
+	Contents          []GeminiContent        `json:"contents"`
+	SystemInstruction *GeminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  GeminiGenerationConfig `json:"generationConfig"`
+}
+
+// GeminiGenerateContentResponse represents a response, or a streamed chunk
+// of one, from the Gemini generateContent API
+type GeminiGenerateContentResponse struct {
+	Candidates []struct {
+		Content      GeminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	// UsageMetadata is cumulative, the last chunk of a stream has the totals
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata"`
+}
+
+// GeminiUsageMetadata is the token count of a generateContent response
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int64 `json:"promptTokenCount"`
+	CandidatesTokenCount int64 `json:"candidatesTokenCount"`
+}
+
+// NewGeminiService creates a new Gemini service
+func NewGeminiService(config LLMServiceConfig) (*GeminiService, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("Gemini API key is required")
+	}
+
+	retryConfig := DefaultRetryConfig()
+	if config.MaxRetries > 0 {
+		retryConfig.MaxRetries = config.MaxRetries
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	baseURL := DefaultGeminiBaseURL
+	if config.BaseURL != "" {
+		baseURL = config.BaseURL
+	}
+
+	modelName := DefaultGeminiModel
+	if config.ModelName != "" {
+		modelName = config.ModelName
+	}
+
+	modelURL := strings.TrimSuffix(baseURL, "/") + "/models/" + url.PathEscape(modelName)
+
+	baseClient := &BaseLLMClient{
+		APIKey:         config.APIKey,
+		APIEndpoint:    modelURL + ":generateContent",
+		StreamEndpoint: modelURL + ":streamGenerateContent?alt=sse",
+		ModelName:      modelName,
+		Provider:       string(ProviderGemini),
+		AuthScheme:     AuthQuery,
+		AuthHeaderName: "key",
+		Headers:        config.Headers,
+		HTTPClient:     NewHTTPClient(timeout),
+		// Streams are bounded by the caller's context instead
+		StreamHTTPClient: NewHTTPClient(0),
+		Metrics:          NewLLMMetrics(),
+		RetryConfig:      retryConfig,
+		Prompts:          promptRegistry(config),
+		Price:            modelPrice(config, string(ProviderGemini), modelName),
+	}
+
+	return &GeminiService{
+		BaseClient: baseClient,
+	}, nil
+}
+
+// Model implements ModelIdentifier
+func (s *GeminiService) Model() string {
+	return s.BaseClient.Provider + "/" + s.BaseClient.ModelName
+}
+
+// ProcessText implements LLMService.ProcessText
+func (s *GeminiService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	content, usage, err := s.complete(ctx, prompt.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content, usage, s.complete)
+}
+
+// ProcessTextStream implements LLMService.ProcessTextStream. A repair of
+// invalid output isn't streamed.
+func (s *GeminiService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	var content strings.Builder
+	var usage GeminiUsageMetadata
+	err = s.BaseClient.SendStreamRequest(ctx, s.request(prompt.Messages), func(data []byte) error {
+		var chunk GeminiGenerateContentResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.UsageMetadata != nil {
+			usage = *chunk.UsageMetadata
+		}
+
+		delta, err := s.text(&chunk)
+		if err != nil {
+			return err
+		}
+		if delta != "" {
+			content.WriteString(delta)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		}
+		return nil
+	})
+	recorded := s.BaseClient.recordUsage(usage.PromptTokenCount, usage.CandidatesTokenCount)
+	if err != nil {
+		return nil, withUsage(err, recorded)
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content.String(), recorded, s.complete)
+}
+
+// complete sends a conversation and returns the model's reply
+func (s *GeminiService) complete(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+	var response GeminiGenerateContentResponse
+	if err := s.BaseClient.SendRequest(ctx, s.request(messages), &response); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+
+	var usage GeminiUsageMetadata
+	if response.UsageMetadata != nil {
+		usage = *response.UsageMetadata
+	}
+	recorded := s.BaseClient.recordUsage(usage.PromptTokenCount, usage.CandidatesTokenCount)
+
+	content, err := s.text(&response)
+	if err != nil {
+		return "", models.TokenUsage{}, withUsage(err, recorded)
+	}
+	if content == "" {
+		return "", models.TokenUsage{}, withUsage(ErrInvalidResponse, recorded)
+	}
+
+	return content, recorded, nil
+}
+
+// request builds a generateContent request asking for JSON matching the
+// schema. The system message becomes the system instruction and the
+// assistant's turns are the model's.
+func (s *GeminiService) request(messages []Message) GeminiGenerateContentRequest {
+	request := GeminiGenerateContentRequest{
+		GenerationConfig: GeminiGenerationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   ProcessedContentSchema.GeminiSchema(),
+		},
+	}
+
+	for _, message := range messages {
+		parts := []GeminiPart{{Text: message.Content}}
+		switch message.Role {
+		case "system":
+			request.SystemInstruction = &GeminiContent{Parts: parts}
+		case "assistant":
+			request.Contents = append(request.Contents, GeminiContent{Role: "model", Parts: parts})
+		default:
+			request.Contents = append(request.Contents, GeminiContent{Role: "user", Parts: parts})
+		}
+	}
+
+	return request
+}
+
+// text joins the text parts of the first candidate. Blocked prompts and
+// responses won't pass on a retry and are reported as non-retryable errors.
+func (s *GeminiService) text(response *GeminiGenerateContentResponse) (string, error) {
+	if reason := response.PromptFeedback.BlockReason; reason != "" {
+		return "", NewLLMError(http.StatusBadRequest, "prompt blocked: "+reason, s.BaseClient.Provider, false)
+	}
+	if len(response.Candidates) == 0 {
+		return "", nil
+	}
+
+	candidate := response.Candidates[0]
+	switch candidate.FinishReason {
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT":
+		return "", NewLLMError(http.StatusBadRequest, "response blocked: "+candidate.FinishReason, s.BaseClient.Provider, false)
+	}
+
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String(), nil
+}
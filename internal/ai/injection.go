@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Flags raised by DetectInjection
+const (
+	// FlagInstructionOverride marks text telling the model to drop its instructions
+	FlagInstructionOverride = "instruction_override"
+	// FlagRoleMarker marks text imitating chat roles or model control tokens
+	FlagRoleMarker = "role_marker"
+	// FlagPromptExtraction marks text asking the model to reveal its prompt
+	FlagPromptExtraction = "prompt_extraction"
+	// FlagDelimiter marks text trying to close the block it is embedded in
+	FlagDelimiter = "delimiter"
+	// FlagOutputInjection marks text that looks like the model's JSON output
+	FlagOutputInjection = "output_injection"
+)
+
+// learnerTextTag delimits the learner's text in the prompt
+const learnerTextTag = "learner_text"
+
+// injectionPatterns are matched against the lower-cased text with runs of
+// spaces collapsed; line breaks are kept for the role markers. They cover
+// English and the most common target languages; the point is to flag notes
+// for review, not to be exhaustive.
+var injectionPatterns = []struct {
+	flag    string
+	pattern *regexp.Regexp
+}{
+	{FlagInstructionOverride, regexp.MustCompile(`\b(ignore|disregard|forget|override|skip)\b.{0,30}\b(previous|prior|above|earlier|preceding|all|your|system)\b.{0,20}\b(instructions?|prompts?|rules|directions|messages|context)\b`)},
+	{FlagInstructionOverride, regexp.MustCompile(`\b(you are now|from now on,? you( are| will)?|act as|pretend (to be|you are)|roleplay as)( an?| the| my)? (ai|assistant|model|chatbot|bot|dan|unrestricted|jailbroken|uncensored)\b`)},
+	{FlagInstructionOverride, regexp.MustCompile(`\bnew (instructions|system prompt)\s*:`)},
+	{FlagInstructionOverride, regexp.MustCompile(`\b(ignorier(e|en)?|vergiss)\b.{0,30}\b(anweisungen|instruktionen|regeln)\b`)},
+	{FlagInstructionOverride, regexp.MustCompile(`\b(ignore[zr]?|oublie[zr]?)\b.{0,30}\b(instructions|consignes|règles)\b`)},
+	{FlagInstructionOverride, regexp.MustCompile(`\b(ignora|olvida)\b.{0,30}\b(instrucciones|reglas)\b`)},
+	{FlagRoleMarker, regexp.MustCompile(`(^|\n)\s*(system|assistant|user|developer)\s*:`)},
+	{FlagRoleMarker, regexp.MustCompile(`<\|(im_start|im_end|system|endoftext)\|>|\[/?inst\]|<</?sys>>|</?(system|assistant)>|###\s*(instruction|system|response)`)},
+	{FlagPromptExtraction, regexp.MustCompile(`\b(reveal|show|print|repeat|output|tell me)\b.{0,20}\b(your|the)\b.{0,10}\b(system prompt|prompt|instructions)\b`)},
+	{FlagDelimiter, regexp.MustCompile(`<\s*/?\s*` + learnerTextTag + `|` + "```")},
+	{FlagOutputInjection, regexp.MustCompile(`"\s*(content|tags|analysis|vocabulary)\s*"\s*:`)},
+}
+
+// DetectInjection flags text that looks like an attempt to hijack the
+// analysis prompt. Learners may legitimately write such sentences, so the
+// flags are only recorded, the text is still processed.
+func DetectInjection(text string) []string {
+	normalized := strings.ToLower(text)
+	normalized = strings.Join(strings.FieldsFunc(normalized, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\r'
+	}), " ")
+
+	var flags []string
+	for _, p := range injectionPatterns {
+		if containsString(flags, p.flag) {
+			continue
+		}
+		if p.pattern.MatchString(normalized) {
+			flags = append(flags, p.flag)
+		}
+	}
+	return flags
+}
+
+// delimiterPattern matches the learner text tags in any case and spacing
+var delimiterPattern = regexp.MustCompile(`(?i)<(\s*/?\s*` + learnerTextTag + `)`)
+
+// fenceLearnerText wraps the learner's text in the delimiters the analysis
+// prompt refers to. Tags inside the text are escaped so it can't close the
+// block early.
+func fenceLearnerText(text string) string {
+	escaped := delimiterPattern.ReplaceAllString(text, "&lt;$1")
+	return "<" + learnerTextTag + ">\n" + escaped + "\n</" + learnerTextTag + ">"
+}
@@ -1,11 +1,28 @@
 package ai
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-// NewLLMMetrics creates and registers metrics for LLM services
+var (
+	llmMetricsOnce sync.Once
+	llmMetrics     *LLMMetrics
+)
+
+// NewLLMMetrics returns the metrics for LLM services, creating and
+// registering them on first use. They are shared by all providers, which are
+// told apart by the provider label.
 func NewLLMMetrics() *LLMMetrics {
+	llmMetricsOnce.Do(func() {
+		llmMetrics = newLLMMetrics()
+	})
+	return llmMetrics
+}
+
+// newLLMMetrics creates and registers metrics for LLM services
+func newLLMMetrics() *LLMMetrics {
 	requestDuration := prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "llm_request_duration_seconds",
@@ -23,11 +40,38 @@ func NewLLMMetrics() *LLMMetrics {
 		[]string{"provider", "status"},
 	)
 
+	repairCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_output_repairs_total",
+			Help: "Total number of repair re-prompts after model output failed schema validation",
+		},
+		[]string{"provider", "result"},
+	)
+
+	tokenCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_tokens_total",
+			Help: "Total number of tokens consumed by LLM API requests",
+		},
+		[]string{"provider", "model", "type"},
+	)
+
+	costCounter := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "llm_cost_usd_total",
+			Help: "Total cost of LLM API requests in USD, per the configured price table",
+		},
+		[]string{"provider", "model"},
+	)
+
 	// Register metrics with Prometheus
-	prometheus.MustRegister(requestDuration, requestCounter)
+	prometheus.MustRegister(requestDuration, requestCounter, repairCounter, tokenCounter, costCounter)
 
 	return &LLMMetrics{
 		RequestDuration: requestDuration,
 		RequestCounter:  requestCounter,
+		RepairCounter:   repairCounter,
+		TokenCounter:    tokenCounter,
+		CostCounter:     costCounter,
 	}
 }
@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResponseFormatType is how structured output is requested from an
+// OpenAI-compatible API
+type ResponseFormatType string
+
+const (
+	// ResponseFormatJSONSchema requests output matching a JSON schema
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+	// ResponseFormatJSONObject requests any JSON object (JSON mode)
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	// ResponseFormatNone leaves the format to the prompt alone
+	ResponseFormatNone ResponseFormatType = "none"
+)
+
+// ProviderPreset holds the defaults of a well-known OpenAI-compatible API
+type ProviderPreset struct {
+	BaseURL        string
+	ModelName      string
+	ResponseFormat ResponseFormatType
+	// RequiresAPIKey is false for servers that usually run without
+	// authentication, e.g. a local vLLM or Ollama
+	RequiresAPIKey bool
+}
+
+// ProviderPresets are the OpenAI-compatible APIs known by name
+var ProviderPresets = map[ProviderType]ProviderPreset{
+	ProviderOpenAI: {
+		BaseURL:        "https://api.openai.com/v1",
+		ModelName:      "gpt-4o",
+		ResponseFormat: ResponseFormatJSONSchema,
+		RequiresAPIKey: true,
+	},
+	ProviderDeepseek: {
+		BaseURL:        "https://api.deepseek.com/v1",
+		ModelName:      "deepseek-chat",
+		ResponseFormat: ResponseFormatJSONObject,
+		RequiresAPIKey: true,
+	},
+	ProviderOpenAICompatible: {
+		ResponseFormat: ResponseFormatJSONSchema,
+	},
+}
+
+// OpenAICompatibleService implements LLMService for any API speaking the
+// OpenAI chat completions protocol: OpenAI and DeepSeek, but also
+// self-hosted servers like vLLM, Ollama or LM Studio
+type OpenAICompatibleService struct {
+	BaseClient     *BaseLLMClient
+	ResponseFormat ResponseFormatType
+}
+
+// ChatCompletionRequest represents a request to an OpenAI-compatible chat completions API
+type ChatCompletionRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+}
+
+// StreamOptions asks for the usage to be sent with the last chunk of a stream
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ResponseFormat requests structured output from a chat completions API
+type ResponseFormat struct {
+	Type       string                `json:"type"`
+	JSONSchema *ResponseFormatSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseFormatSchema names the schema the output must match
+type ResponseFormatSchema struct {
+	Name   string      `json:"name"`
+	Schema *JSONSchema `json:"schema"`
+}
+
+// ChatCompletionResponse represents a response from an OpenAI-compatible chat completions API
+type ChatCompletionResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage *ChatCompletionUsage `json:"usage"`
+}
+
+// NewOpenAICompatibleService creates a service for an OpenAI-compatible API.
+// Settings missing from the config are taken from the provider's preset.
+func NewOpenAICompatibleService(config LLMServiceConfig) (*OpenAICompatibleService, error) {
+	preset := ProviderPresets[config.ProviderType]
+
+	baseURL := preset.BaseURL
+	if config.BaseURL != "" {
+		baseURL = config.BaseURL
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("%s base URL is required", config.ProviderType)
+	}
+
+	modelName := preset.ModelName
+	if config.ModelName != "" {
+		modelName = config.ModelName
+	}
+	if modelName == "" {
+		return nil, fmt.Errorf("%s model name is required", config.ProviderType)
+	}
+
+	switch config.AuthScheme {
+	case "", AuthBearer, AuthNone:
+	case AuthHeader, AuthQuery:
+		if config.AuthHeaderName == "" {
+			return nil, fmt.Errorf("%s auth header or parameter name is required", config.ProviderType)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported auth scheme: %s", config.AuthScheme)
+	}
+
+	if preset.RequiresAPIKey && config.APIKey == "" && config.AuthScheme != AuthNone {
+		return nil, fmt.Errorf("%s API key is required", config.ProviderType)
+	}
+
+	retryConfig := DefaultRetryConfig()
+	if config.MaxRetries > 0 {
+		retryConfig.MaxRetries = config.MaxRetries
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	baseClient := &BaseLLMClient{
+		APIKey:         config.APIKey,
+		APIEndpoint:    strings.TrimSuffix(baseURL, "/") + "/chat/completions",
+		ModelName:      modelName,
+		Provider:       string(config.ProviderType),
+		AuthScheme:     config.AuthScheme,
+		AuthHeaderName: config.AuthHeaderName,
+		Headers:        config.Headers,
+		HTTPClient:     NewHTTPClient(timeout),
+		// Streams are bounded by the caller's context instead
+		StreamHTTPClient: NewHTTPClient(0),
+		Metrics:          NewLLMMetrics(),
+		RetryConfig:      retryConfig,
+		Prompts:          promptRegistry(config),
+		Price:            modelPrice(config, string(config.ProviderType), modelName),
+	}
+
+	responseFormat := preset.ResponseFormat
+	if config.ResponseFormat != "" {
+		responseFormat = config.ResponseFormat
+	}
+	switch responseFormat {
+	case ResponseFormatJSONSchema, ResponseFormatJSONObject, ResponseFormatNone:
+	default:
+		return nil, fmt.Errorf("unsupported response format: %s", responseFormat)
+	}
+
+	return &OpenAICompatibleService{
+		BaseClient:     baseClient,
+		ResponseFormat: responseFormat,
+	}, nil
+}
+
+// Model implements ModelIdentifier
+func (s *OpenAICompatibleService) Model() string {
+	return s.BaseClient.Provider + "/" + s.BaseClient.ModelName
+}
+
+// ProcessText implements LLMService.ProcessText
+func (s *OpenAICompatibleService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	content, usage, err := s.complete(ctx, prompt.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content, usage, s.complete)
+}
+
+// ProcessTextStream implements LLMService.ProcessTextStream. A repair of
+// invalid output isn't streamed.
+func (s *OpenAICompatibleService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	prompt, err := renderAnalysisPrompt(s.BaseClient.Prompts, text, sourceLanguage, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+	request := s.request(prompt.Messages)
+	request.Stream = true
+	request.StreamOptions = &StreamOptions{IncludeUsage: true}
+
+	content, usage, err := s.BaseClient.StreamChatCompletion(ctx, request, onDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	return completeStructured(ctx, s.BaseClient, prompt, content, usage, s.complete)
+}
+
+// complete sends a conversation and returns the model's reply
+func (s *OpenAICompatibleService) complete(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+	// Send request
+	var response ChatCompletionResponse
+	if err := s.BaseClient.SendRequest(ctx, s.request(messages), &response); err != nil {
+		return "", models.TokenUsage{}, err
+	}
+
+	// Process response
+	var usage ChatCompletionUsage
+	if response.Usage != nil {
+		usage = *response.Usage
+	}
+	recorded := s.BaseClient.recordUsage(usage.PromptTokens, usage.CompletionTokens)
+	if len(response.Choices) == 0 {
+		return "", models.TokenUsage{}, withUsage(ErrInvalidResponse, recorded)
+	}
+
+	return response.Choices[0].Message.Content, recorded, nil
+}
+
+// request builds a chat completion request asking for structured output in
+// the provider's format
+func (s *OpenAICompatibleService) request(messages []Message) ChatCompletionRequest {
+	request := ChatCompletionRequest{
+		Model:    s.BaseClient.ModelName,
+		Messages: messages,
+	}
+
+	switch s.ResponseFormat {
+	case ResponseFormatJSONSchema:
+		request.ResponseFormat = &ResponseFormat{
+			Type: string(ResponseFormatJSONSchema),
+			JSONSchema: &ResponseFormatSchema{
+				Name:   ProcessedContentSchemaName,
+				Schema: ProcessedContentSchema,
+			},
+		}
+	case ResponseFormatJSONObject:
+		request.ResponseFormat = &ResponseFormat{Type: string(ResponseFormatJSONObject)}
+	}
+
+	return request
+}
@@ -7,69 +7,40 @@ import (
 	"strings"
 )
 
-// parseJSONContent extracts structured content from LLM JSON response
+// ParseJSONContent validates the model's output against ProcessedContentSchema
+// and decodes it. Markdown code fences around the object are tolerated, any
+// other deviation is reported as a *SchemaValidationError.
 func ParseJSONContent(content string) (*dto.ProcessedContent, error) {
-	// Strip markdown code blocks if present
-	if strings.HasPrefix(content, "```") {
-		// Find the position of the first opening brace
-		startPos := strings.Index(content, "{")
-		if startPos != -1 {
-			// Find the position of the last closing brace
-			endPos := strings.LastIndex(content, "}")
-			if endPos != -1 && endPos > startPos {
-				content = content[startPos : endPos+1]
-			}
-		}
+	content = stripCodeFence(content)
+
+	var raw interface{}
+	if err := json.Unmarshal([]byte(content), &raw); err != nil {
+		return nil, &SchemaValidationError{Problems: []string{fmt.Sprintf("$: not valid JSON: %v", err)}}
+	}
+	if problems := ProcessedContentSchema.Validate(raw, "$"); len(problems) > 0 {
+		return nil, &SchemaValidationError{Problems: problems}
 	}
 
-	// Try parsing as a direct JSON object first
 	var processedContent dto.ProcessedContent
-	if err := json.Unmarshal([]byte(content), &processedContent); err == nil {
-		// Successful direct parsing
-		if processedContent.Content != "" && len(processedContent.Tags) > 0 {
-			return &processedContent, nil
-		}
+	if err := json.Unmarshal([]byte(content), &processedContent); err != nil {
+		return nil, &SchemaValidationError{Problems: []string{err.Error()}}
 	}
 
-	// If direct parsing failed, try the flexible approach
-	var rawContent map[string]interface{}
-	if err := json.Unmarshal([]byte(content), &rawContent); err != nil {
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
-	}
+	return &processedContent, nil
+}
 
-	// Process the content field
-	var finalContent string
-	if contentVal, exists := rawContent["content"]; exists {
-		switch c := contentVal.(type) {
-		case string:
-			finalContent = c
-		case map[string]interface{}:
-			contentBytes, err := json.MarshalIndent(c, "", "  ")
-			if err != nil {
-				return nil, fmt.Errorf("failed to process content object: %w", err)
-			}
-			finalContent = string(contentBytes)
-		default:
-			return nil, fmt.Errorf("unexpected content format in LLM response")
-		}
-	} else {
-		return nil, fmt.Errorf("content field missing in LLM response")
+// stripCodeFence removes a markdown code fence wrapped around the output
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
 	}
 
-	// Process tags
-	var tags []string
-	if tagsVal, exists := rawContent["tags"]; exists {
-		if tagsSlice, ok := tagsVal.([]interface{}); ok {
-			for _, tag := range tagsSlice {
-				if tagStr, ok := tag.(string); ok {
-					tags = append(tags, tagStr)
-				}
-			}
-		}
+	// Drop the opening fence line, including a language tag like ```json
+	if newline := strings.Index(content, "\n"); newline != -1 {
+		content = content[newline+1:]
+	} else {
+		content = strings.TrimPrefix(content, "```")
 	}
-
-	return &dto.ProcessedContent{
-		Content: finalContent,
-		Tags:    tags,
-	}, nil
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
 }
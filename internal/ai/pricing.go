@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ModelPrice is what a model costs in USD per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// Cost returns the price of a call
+func (p ModelPrice) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.InputPerMillion + float64(completionTokens)*p.OutputPerMillion) / 1e6
+}
+
+// DefaultPrices are list prices of the providers' default models, keyed by
+// model name. Override or extend them with LLM_PRICES.
+var DefaultPrices = map[string]ModelPrice{
+	"gpt-4o":                   {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4o-mini":              {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	"deepseek-chat":            {InputPerMillion: 0.27, OutputPerMillion: 1.10},
+	"claude-3-5-sonnet-latest": {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"gemini-1.5-flash":         {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+}
+
+// ParsePrices parses a price table of comma-separated
+// "<model>=<input>/<output>" entries in USD per million tokens. The model may
+// be qualified by its provider, e.g. "openai-compatible/llama3.1=0/0".
+func ParsePrices(raw string) (map[string]ModelPrice, error) {
+	prices := make(map[string]ModelPrice)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, price, ok := strings.Cut(entry, "=")
+		input, output, hasOutput := strings.Cut(price, "/")
+		inputPrice, inputErr := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		outputPrice, outputErr := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if !ok || !hasOutput || strings.TrimSpace(model) == "" || inputErr != nil || outputErr != nil || inputPrice < 0 || outputPrice < 0 {
+			return nil, fmt.Errorf("invalid price %q, expected <model>=<input>/<output> per million tokens", entry)
+		}
+		prices[strings.TrimSpace(model)] = ModelPrice{InputPerMillion: inputPrice, OutputPerMillion: outputPrice}
+	}
+	return prices, nil
+}
+
+// modelPrice returns the price of a provider's model, or nil if it isn't
+// known, in which case its calls are counted without a cost
+func modelPrice(config LLMServiceConfig, provider, model string) *ModelPrice {
+	price, ok := lookupPrice(config.Prices, provider, model)
+	if !ok {
+		log.Printf("No price known for %s/%s, its usage will be recorded without cost", provider, model)
+		return nil
+	}
+	return &price
+}
+
+// lookupPrice finds a model's price, preferring configured prices over the
+// defaults and provider-qualified entries over bare model names
+func lookupPrice(prices map[string]ModelPrice, provider, model string) (ModelPrice, bool) {
+	for _, table := range []map[string]ModelPrice{prices, DefaultPrices} {
+		if price, ok := table[provider+"/"+model]; ok {
+			return price, true
+		}
+		if price, ok := table[model]; ok {
+			return price, true
+		}
+	}
+	return ModelPrice{}, false
+}
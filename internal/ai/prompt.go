@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/prompts"
+)
+
+// analysisPrompt is a rendered conversation asking a model to analyze a text
+type analysisPrompt struct {
+	Messages []Message
+	// Version is the ID of the analysis template, recorded on the note
+	Version        string
+	TargetLanguage string
+}
+
+// renderAnalysisPrompt renders the conversation analyzing a text: the system
+// and analysis prompts as system message, the delimited text as user message.
+// Analysis templates that include the text themselves, like v1, are sent as
+// the user message instead, after the system prompt.
+func renderAnalysisPrompt(registry *prompts.Registry, text, sourceLanguage, targetLanguage string) (*analysisPrompt, error) {
+	system, err := registry.Resolve(prompts.System, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+	analysis, err := registry.Resolve(prompts.Analysis, targetLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	fenced := fenceLearnerText(text)
+	data := prompts.Data{
+		Text:           fenced,
+		NativeLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		PartsOfSpeech:  models.PartsOfSpeech,
+	}
+	systemText, err := system.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	analysisText, err := analysis.Render(data)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []Message{
+		{Role: "system", Content: systemText + "\n\n" + analysisText},
+		{Role: "user", Content: fenced},
+	}
+	if analysis.InlinesText {
+		messages = []Message{
+			{Role: "system", Content: systemText},
+			{Role: "user", Content: analysisText},
+		}
+	}
+
+	return &analysisPrompt{
+		Messages:       messages,
+		Version:        analysis.ID(),
+		TargetLanguage: targetLanguage,
+	}, nil
+}
+
+// renderRepairPrompt renders the prompt asking the model to fix output that
+// failed validation
+func renderRepairPrompt(registry *prompts.Registry, targetLanguage string, err *SchemaValidationError) (string, error) {
+	repair, resolveErr := registry.Resolve(prompts.Repair, targetLanguage)
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return repair.Render(prompts.Data{
+		TargetLanguage: targetLanguage,
+		Problems:       err.Problems,
+	})
+}
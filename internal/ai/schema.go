@@ -0,0 +1,235 @@
+package ai
+
+import (
+	"ai-language-notes/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSONSchema is the subset of JSON Schema used to declare the output
+// expected from the models and to validate what they return
+type JSONSchema struct {
+	Type                 string                 `json:"type"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+}
+
+// ProcessedContentSchemaName names the schema in provider requests
+const ProcessedContentSchemaName = "processed_content"
+
+// ProcessedContentSchema declares the JSON object the models must return
+var ProcessedContentSchema = objectSchema(map[string]*JSONSchema{
+	"content": {
+		Type:        "string",
+		Description: "Readable summary of the analysis for the learner",
+		MinLength:   intPtr(1),
+	},
+	"tags": {
+		Type:        "array",
+		Description: "3-5 relevant single-word tags for categorizing the note",
+		Items:       &JSONSchema{Type: "string", MinLength: intPtr(1)},
+		MinItems:    intPtr(1),
+		MaxItems:    intPtr(10),
+	},
+	"analysis": objectSchema(map[string]*JSONSchema{
+		"vocabulary": arraySchema("Interesting words and expressions of the text", objectSchema(map[string]*JSONSchema{
+			"term":  nonEmptyString("The word or expression as it appears in the text"),
+			"lemma": nonEmptyString("Its dictionary form"),
+			"partOfSpeech": {
+				Type: "string",
+				Enum: models.PartsOfSpeech,
+			},
+			"translation": nonEmptyString("Translation into the learner's native language"),
+		})),
+		"grammar": arraySchema("Grammar points illustrated by the text", objectSchema(map[string]*JSONSchema{
+			"topic":       nonEmptyString("Name of the grammar point"),
+			"explanation": nonEmptyString("Explanation in the learner's native language"),
+			"example":     {Type: "string", Description: "Example sentence in the target language"},
+		})),
+		"culturalContext": {
+			Type:        "string",
+			Description: "Cultural context of the text, empty if there is none worth mentioning",
+		},
+		"alternatives": arraySchema("Other ways to express the same idea", objectSchema(map[string]*JSONSchema{
+			"text":        nonEmptyString("The alternative in the target language"),
+			"explanation": {Type: "string", Description: "How it differs, e.g. in register"},
+		})),
+		"mistakes": arraySchema("Mistakes learners commonly make with this text", objectSchema(map[string]*JSONSchema{
+			"mistake":     nonEmptyString("The incorrect form"),
+			"correction":  nonEmptyString("The correct form"),
+			"explanation": {Type: "string", Description: "Why it is wrong"},
+		})),
+	}),
+})
+
+// Validate checks a decoded JSON value against the schema and returns the
+// problems found, prefixed with their path
+func (s *JSONSchema) Validate(value interface{}, path string) []string {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return problems
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				fail("missing required field %q", name)
+			}
+		}
+		for name, fieldValue := range object {
+			fieldSchema, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					fail("unexpected field %q", name)
+				}
+				continue
+			}
+			problems = append(problems, fieldSchema.Validate(fieldValue, path+"."+name)...)
+		}
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return problems
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(array) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range array {
+				problems = append(problems, s.Items.Validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return problems
+		}
+		if s.MinLength != nil && len(strings.TrimSpace(str)) < *s.MinLength {
+			fail("must not be empty")
+		}
+		if len(s.Enum) > 0 && !containsString(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+
+	case "number":
+		if _, ok := value.(float64); !ok {
+			fail("must be a number")
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+
+	return problems
+}
+
+// GeminiSchema converts the schema to the OpenAPI subset accepted by
+// Gemini's responseSchema, which uses upper-case types and lacks some keywords
+func (s *JSONSchema) GeminiSchema() map[string]interface{} {
+	schema := map[string]interface{}{"type": strings.ToUpper(s.Type)}
+	if s.Description != "" {
+		schema["description"] = s.Description
+	}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]interface{}, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.GeminiSchema()
+		}
+		schema["properties"] = properties
+	}
+	if len(s.Required) > 0 {
+		schema["required"] = s.Required
+	}
+	if s.Items != nil {
+		schema["items"] = s.Items.GeminiSchema()
+	}
+	if s.MinItems != nil {
+		schema["minItems"] = *s.MinItems
+	}
+	if s.MaxItems != nil {
+		schema["maxItems"] = *s.MaxItems
+	}
+	if len(s.Enum) > 0 {
+		schema["format"] = "enum"
+		schema["enum"] = s.Enum
+	}
+	return schema
+}
+
+// SchemaValidationError reports model output that doesn't match the declared schema
+type SchemaValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *SchemaValidationError) Error() string {
+	return "model output doesn't match the schema: " + strings.Join(e.Problems, "; ")
+}
+
+// Unwrap makes the error match ErrInvalidResponse
+func (e *SchemaValidationError) Unwrap() error {
+	return ErrInvalidResponse
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// objectSchema declares an object with all of the given properties required
+// and no others, as strict structured output modes expect
+func objectSchema(properties map[string]*JSONSchema) *JSONSchema {
+	required := make([]string, 0, len(properties))
+	for name := range properties {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+
+	return &JSONSchema{
+		Type:                 "object",
+		Properties:           properties,
+		Required:             required,
+		AdditionalProperties: boolPtr(false),
+	}
+}
+
+// arraySchema declares a possibly empty array
+func arraySchema(description string, items *JSONSchema) *JSONSchema {
+	return &JSONSchema{Type: "array", Description: description, Items: items}
+}
+
+// nonEmptyString declares a string that must not be blank
+func nonEmptyString(description string) *JSONSchema {
+	return &JSONSchema{Type: "string", Description: description, MinLength: intPtr(1)}
+}
+
+func intPtr(v int) *int { return &v }
+
+func boolPtr(v bool) *bool { return &v }
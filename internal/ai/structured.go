@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// completeFunc sends a conversation to a provider and returns the raw output
+// and the call's usage
+type completeFunc func(ctx context.Context, messages []Message) (string, models.TokenUsage, error)
+
+// completeStructured validates a model's output and, if it doesn't match the
+// schema, re-prompts once with the validation errors before giving up. usage
+// is that of the call that produced output; the repair's is added to it.
+// Errors carry the usage spent, see UsageOf.
+func completeStructured(ctx context.Context, client *BaseLLMClient, prompt *analysisPrompt, output string, usage models.TokenUsage, complete completeFunc) (*dto.ProcessedContent, error) {
+	processedContent, err := ParseJSONContent(output)
+	if err == nil {
+		processedContent.Provider = client.Provider
+		processedContent.PromptVersion = prompt.Version
+		processedContent.Usage = usage
+		return processedContent, nil
+	}
+
+	var validationErr *SchemaValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, withUsage(err, usage)
+	}
+	log.Printf("%s output failed validation, asking for a repair: %v", client.Provider, err)
+
+	repairText, err := renderRepairPrompt(client.Prompts, prompt.TargetLanguage, validationErr)
+	if err != nil {
+		return nil, withUsage(err, usage)
+	}
+	repairMessages := append(append([]Message{}, prompt.Messages...),
+		Message{Role: "assistant", Content: output},
+		Message{Role: "user", Content: repairText},
+	)
+	repaired, repairUsage, err := complete(ctx, repairMessages)
+	if err != nil {
+		return nil, withUsage(fmt.Errorf("repair request failed: %w", err), append(UsageOf(err), usage)...)
+	}
+
+	processedContent, err = ParseJSONContent(repaired)
+	if err != nil {
+		client.recordRepair("failed")
+		return nil, withUsage(err, usage.Add(repairUsage))
+	}
+
+	client.recordRepair("repaired")
+	processedContent.Provider = client.Provider
+	processedContent.PromptVersion = prompt.Version
+	processedContent.Usage = usage.Add(repairUsage)
+	return processedContent, nil
+}
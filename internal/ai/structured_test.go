@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/prompts"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestStructuredClient creates a client for completeStructured without metrics
+func newTestStructuredClient() *BaseLLMClient {
+	return &BaseLLMClient{Provider: "test", ModelName: "model", Prompts: prompts.Embedded()}
+}
+
+func newTestPrompt(t *testing.T) *analysisPrompt {
+	t.Helper()
+	prompt, err := renderAnalysisPrompt(prompts.Embedded(), "Hola", "English", "Spanish")
+	if err != nil {
+		t.Fatalf("renderAnalysisPrompt: %v", err)
+	}
+	return prompt
+}
+
+func testUsage(prompt, completion int64) models.TokenUsage {
+	return models.TokenUsage{Model: "test/model", PromptTokens: prompt, CompletionTokens: completion, CostUSD: float64(prompt+completion) / 1000}
+}
+
+func TestCompleteStructuredAddsRepairUsage(t *testing.T) {
+	repair := func(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+		return testAnalysis, testUsage(200, 50), nil
+	}
+
+	result, err := completeStructured(context.Background(), newTestStructuredClient(), newTestPrompt(t), `{"content":""}`, testUsage(100, 20), repair)
+	if err != nil {
+		t.Fatalf("completeStructured: %v", err)
+	}
+	if want := testUsage(300, 70); result.Usage != want {
+		t.Fatalf("Usage = %+v, want %+v", result.Usage, want)
+	}
+}
+
+func TestCompleteStructuredErrorsCarryUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		repair completeFunc
+		want   models.TokenUsage
+	}{
+		{
+			name:   "still invalid after repair",
+			output: `{"content":""}`,
+			repair: func(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+				return `{"content":""}`, testUsage(200, 50), nil
+			},
+			want: testUsage(300, 70),
+		},
+		{
+			name:   "repair request failed",
+			output: `{"content":""}`,
+			repair: func(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+				return "", models.TokenUsage{}, NewLLMError(http.StatusServiceUnavailable, "down", "test", true)
+			},
+			want: testUsage(100, 20),
+		},
+		{
+			name:   "repair response unusable",
+			output: `{"content":""}`,
+			repair: func(ctx context.Context, messages []Message) (string, models.TokenUsage, error) {
+				return "", models.TokenUsage{}, withUsage(ErrInvalidResponse, testUsage(200, 0))
+			},
+			want: testUsage(300, 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := completeStructured(context.Background(), newTestStructuredClient(), newTestPrompt(t), tt.output, testUsage(100, 20), tt.repair)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := UsageOf(err); !reflect.DeepEqual(got, []models.TokenUsage{tt.want}) {
+				t.Fatalf("UsageOf = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsageErrorUnwraps(t *testing.T) {
+	llmErr := NewLLMError(http.StatusBadRequest, "rejected", "test", false)
+	err := fmt.Errorf("wrapped: %w", withUsage(llmErr, testUsage(1, 1)))
+
+	var target *LLMError
+	if !errors.As(err, &target) || target != llmErr {
+		t.Fatal("expected the LLMError to be reachable through the UsageError")
+	}
+	if IsRetryableError(err) {
+		t.Fatal("expected the error to stay non-retryable")
+	}
+	if got := withUsage(ErrInvalidResponse, models.TokenUsage{Model: "test/model"}); got != ErrInvalidResponse {
+		t.Fatalf("withUsage without tokens = %v, want the error unchanged", got)
+	}
+}
+
+func TestOpenAIProcessTextErrorCarriesUsage(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Output missing required fields, before and after the repair
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"{\"content\":\"\"}"}}],"usage":{"prompt_tokens":100,"completion_tokens":20}}`)
+	}))
+	defer server.Close()
+
+	service := newTestOpenAIService(t, server.URL, nil)
+	_, err := service.ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want the request and one repair", requests)
+	}
+	usages := UsageOf(err)
+	if len(usages) != 1 {
+		t.Fatalf("UsageOf = %+v, want the usage of one model", usages)
+	}
+	usage := usages[0]
+	if usage.PromptTokens != 200 || usage.CompletionTokens != 40 || usage.Model != "openai-compatible/test-model" {
+		t.Fatalf("UsageOf = %+v, want 200 prompt and 40 completion tokens of the model", usage)
+	}
+}
+
+// usageService is an LLMService returning a fixed result
+type usageService struct {
+	content *dto.ProcessedContent
+	err     error
+}
+
+func (s *usageService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	content := *s.content
+	return &content, nil
+}
+
+func (s *usageService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	return s.ProcessText(ctx, text, sourceLanguage, targetLanguage)
+}
+
+func TestFallbackKeepsUsagePerModel(t *testing.T) {
+	failedUsage := models.TokenUsage{Model: "first/model", PromptTokens: 100, CompletionTokens: 20, CostUSD: 0.5}
+	servedUsage := models.TokenUsage{Model: "second/model", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.01}
+	failing := &usageService{err: withUsage(NewLLMError(http.StatusServiceUnavailable, "down", "first", true), failedUsage)}
+	serving := &usageService{content: &dto.ProcessedContent{Usage: servedUsage}}
+	// The chain registers its metrics, so it is only created once
+	service := NewFallbackLLMService([]string{"first", "second"}, []LLMService{failing, serving}, CircuitBreakerConfig{FailureThreshold: 100})
+
+	result, err := service.ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if err != nil {
+		t.Fatalf("ProcessText: %v", err)
+	}
+	if result.Usage != servedUsage {
+		t.Fatalf("Usage = %+v, want only the serving provider's %+v", result.Usage, servedUsage)
+	}
+	if want := []models.TokenUsage{failedUsage, servedUsage}; !reflect.DeepEqual(result.AllUsage(), want) {
+		t.Fatalf("AllUsage = %+v, want %+v", result.AllUsage(), want)
+	}
+
+	retriedUsage := models.TokenUsage{Model: "second/model", PromptTokens: 7, CompletionTokens: 3, CostUSD: 0.002}
+	serving.err = withUsage(NewLLMError(http.StatusBadGateway, "bad gateway", "second", true), retriedUsage)
+	_, err = service.ProcessText(context.Background(), "Hola", "English", "Spanish")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := []models.TokenUsage{failedUsage, retriedUsage}; !reflect.DeepEqual(UsageOf(err), want) {
+		t.Fatalf("UsageOf = %+v, want %+v", UsageOf(err), want)
+	}
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeadLetterResponse represents a dead-lettered queue task
+type DeadLetterResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Payload  string    `json:"payload"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	WorkerID string    `json:"workerId"`
+	FailedAt time.Time `json:"failedAt"`
+}
+
+// DeadLetterListResponse represents a page of dead letters
+type DeadLetterListResponse struct {
+	Items  []DeadLetterResponse `json:"items"`
+	Total  int64                `json:"total"`
+	Offset int                  `json:"offset"`
+	Limit  int                  `json:"limit"`
+}
+
+// UpdatePlanRequest moves a user to another plan tier
+type UpdatePlanRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
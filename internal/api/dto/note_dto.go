@@ -13,6 +13,16 @@ type AddNoteRequest struct {
 	Tags         []string `json:"tags,omitempty"`
 }
 
+// ImportNotesRequest represents a bulk import of language notes
+type ImportNotesRequest struct {
+	Notes []AddNoteRequest `json:"notes" binding:"required,min=1,max=100,dive"`
+}
+
+// ImportNotesResponse represents the notes created by a bulk import
+type ImportNotesResponse struct {
+	Notes []NoteResponse `json:"notes"`
+}
+
 // NoteResponse represents the response for note operations
 type NoteResponse struct {
 	ID               uuid.UUID               `json:"id"`
@@ -20,11 +30,47 @@ type NoteResponse struct {
 	GeneratedContent string                  `json:"generatedContent,omitempty"`
 	Status           models.ProcessingStatus `json:"status"`
 	Tags             []string                `json:"tags,omitempty"`
+	Attempts         int                     `json:"attempts"`
+	NextRetryAt      *time.Time              `json:"nextRetryAt,omitempty"`
+	Provider         string                  `json:"provider,omitempty"`
+	PromptVersion    string                  `json:"promptVersion,omitempty"`
+	InputFlags       []string                `json:"inputFlags,omitempty"`
+	Usage            *models.TokenUsage      `json:"usage,omitempty"`
+	Analysis         *models.NoteAnalysis    `json:"analysis,omitempty"`
 	CreatedAt        time.Time               `json:"createdAt"`
 }
 
+// NoteStreamDelta is a piece of LLM output relayed by the note stream endpoint
+type NoteStreamDelta struct {
+	Content string `json:"content"`
+}
+
+// NoteStreamError ends a note stream whose processing failed. The note, if
+// it was saved, has been queued for regular processing.
+type NoteStreamError struct {
+	Error  string     `json:"error"`
+	NoteID *uuid.UUID `json:"noteId,omitempty"`
+}
+
 // ProcessedContent represents the structured content from LLM processing
 type ProcessedContent struct {
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	Content  string               `json:"content"`
+	Tags     []string             `json:"tags"`
+	Analysis *models.NoteAnalysis `json:"analysis"`
+	// Provider is the LLM provider that generated the content. It is set by
+	// the services, never taken from the model's output.
+	Provider string `json:"provider,omitempty"`
+	// PromptVersion identifies the analysis prompt, set by the services too
+	PromptVersion string `json:"promptVersion,omitempty"`
+	// Usage is what generating the content consumed, zero if it was cached
+	Usage models.TokenUsage `json:"usage"`
+	// FailedUsage is what providers that failed before this one consumed,
+	// one entry per model
+	FailedUsage []models.TokenUsage `json:"-"`
+}
+
+// AllUsage returns what generating the content consumed per model, the
+// providers that failed first included
+func (c *ProcessedContent) AllUsage() []models.TokenUsage {
+	return models.AddUsage(append([]models.TokenUsage(nil), c.FailedUsage...), c.Usage)
 }
@@ -0,0 +1,28 @@
+package dto
+
+// UsageTotals sums LLM usage
+type UsageTotals struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	TotalTokens      int64   `json:"totalTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// UsageDayResponse represents the user's usage of a model on a day
+type UsageDayResponse struct {
+	Date             string  `json:"date"` // YYYY-MM-DD, UTC
+	Model            string  `json:"model"`
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// UsageResponse represents the user's LLM usage over a range of days
+type UsageResponse struct {
+	From  string             `json:"from"`
+	To    string             `json:"to"`
+	Total UsageTotals        `json:"total"`
+	Days  []UsageDayResponse `json:"days"`
+}
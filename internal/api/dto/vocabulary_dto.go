@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// VocabularyItemResponse represents a lemma of the user's vocabulary
+// aggregated across their notes
+type VocabularyItemResponse struct {
+	Lemma        string    `json:"lemma"`
+	PartOfSpeech string    `json:"partOfSpeech"`
+	Translation  string    `json:"translation"`
+	NoteCount    int64     `json:"noteCount"`
+	LastSeenAt   time.Time `json:"lastSeenAt"`
+}
+
+// VocabularyListResponse represents a page of the user's vocabulary
+type VocabularyListResponse struct {
+	Items  []VocabularyItemResponse `json:"items"`
+	Total  int64                    `json:"total"`
+	Offset int                      `json:"offset"`
+	Limit  int                      `json:"limit"`
+}
@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"ai-language-notes/internal/models"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Secret string   `json:"secret,omitempty" binding:"omitempty,min=16,max=255"`
+}
+
+// UpdateWebhookRequest represents the request to change a webhook
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// WebhookResponse represents a webhook. The secret is only included in
+// the response to its creation.
+type WebhookResponse struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// WebhookDeliveryResponse represents one entry of a webhook's delivery log
+type WebhookDeliveryResponse struct {
+	ID             uuid.UUID                    `json:"id"`
+	EventType      string                       `json:"eventType"`
+	Payload        json.RawMessage              `json:"payload"`
+	Status         models.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                          `json:"attempts"`
+	NextAttemptAt  *time.Time                   `json:"nextAttemptAt,omitempty"`
+	ResponseStatus int                          `json:"responseStatus,omitempty"`
+	LastError      string                       `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time                   `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time                    `json:"createdAt"`
+}
+
+// WebhookDeliveryListResponse represents a page of a webhook's delivery log
+type WebhookDeliveryListResponse struct {
+	Items  []WebhookDeliveryResponse `json:"items"`
+	Total  int64                     `json:"total"`
+	Offset int                       `json:"offset"`
+	Limit  int                       `json:"limit"`
+}
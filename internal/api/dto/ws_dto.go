@@ -0,0 +1,29 @@
+package dto
+
+import "encoding/json"
+
+// WebSocket message types
+const (
+	WSTypeSubscribe   = "subscribe"
+	WSTypeUnsubscribe = "unsubscribe"
+	WSTypeSubscribed  = "subscribed"
+	WSTypeEvent       = "event"
+	WSTypePing        = "ping"
+	WSTypePong        = "pong"
+	WSTypeError       = "error"
+)
+
+// WSClientMessage is a message sent by a WebSocket client
+type WSClientMessage struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// WSServerMessage is a message sent to a WebSocket client
+type WSServerMessage struct {
+	Type   string          `json:"type"`
+	Topic  string          `json:"topic,omitempty"`
+	Topics []string        `json:"topics,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
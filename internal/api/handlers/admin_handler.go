@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// AdminHandler handles operational requests restricted to admins
+type AdminHandler struct {
+	deadLetterService services.DeadLetterService
+	userService       services.UserService
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(deadLetterService services.DeadLetterService, userService services.UserService) *AdminHandler {
+	return &AdminHandler{
+		deadLetterService: deadLetterService,
+		userService:       userService,
+	}
+}
+
+// ListDeadLetters returns a page of dead-lettered tasks, newest first
+func (h *AdminHandler) ListDeadLetters(c *gin.Context) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	entries, total, err := h.deadLetterService.ListDeadLetters(c.Request.Context(), offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead letters"})
+		return
+	}
+
+	items := make([]dto.DeadLetterResponse, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, convertDeadLetterToResponse(entry))
+	}
+
+	c.JSON(http.StatusOK, dto.DeadLetterListResponse{
+		Items:  items,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// GetDeadLetter returns a single dead-lettered task
+func (h *AdminHandler) GetDeadLetter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter ID format"})
+		return
+	}
+
+	entry, err := h.deadLetterService.GetDeadLetter(c.Request.Context(), id)
+	if err != nil {
+		if err == services.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead letter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertDeadLetterToResponse(entry))
+}
+
+// RequeueDeadLetter puts a dead-lettered task back onto the processing queue
+func (h *AdminHandler) RequeueDeadLetter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter ID format"})
+		return
+	}
+
+	err = h.deadLetterService.RequeueDeadLetter(c.Request.Context(), id)
+	if err != nil {
+		if err == services.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		if err == queue.ErrInvalidPayload {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if err == queue.ErrTaskQueued {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue dead letter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter requeued successfully"})
+}
+
+// DeleteDeadLetter permanently removes a dead-lettered task
+func (h *AdminHandler) DeleteDeadLetter(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter ID format"})
+		return
+	}
+
+	err = h.deadLetterService.DeleteDeadLetter(c.Request.Context(), id)
+	if err != nil {
+		if err == services.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete dead letter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letter deleted successfully"})
+}
+
+// PurgeDeadLetters removes every dead-lettered task
+func (h *AdminHandler) PurgeDeadLetters(c *gin.Context) {
+	purged, err := h.deadLetterService.PurgeDeadLetters(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dead letters purged successfully", "purged": purged})
+}
+
+// Helper function to convert a dead letter to its response DTO
+func convertDeadLetterToResponse(entry *queue.DeadLetter) dto.DeadLetterResponse {
+	return dto.DeadLetterResponse{
+		ID:       entry.ID,
+		Payload:  entry.Payload,
+		Error:    entry.Error,
+		Attempts: entry.Attempts,
+		WorkerID: entry.WorkerID,
+		FailedAt: entry.FailedAt,
+	}
+}
+
+// UpdateUserPlan moves a user to another plan tier
+func (h *AdminHandler) UpdateUserPlan(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var req dto.UpdatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userService.UpdatePlan(id, req.Plan)
+	if err != nil {
+		if err == services.ErrInvalidPlan {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err == services.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -1,16 +1,28 @@
 package handlers
 
 import (
+	"ai-language-notes/internal/ai"
 	"ai-language-notes/internal/api/dto"
 	"ai-language-notes/internal/api/middleware"
 	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/quota"
 	"ai-language-notes/internal/services"
+	"context"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// sseKeepAliveInterval is how often an idle event stream sends a comment so
+// proxies don't close the connection
+const sseKeepAliveInterval = 15 * time.Second
+
 // NoteHandler handles note-related requests
 type NoteHandler struct {
 	noteService services.NoteService
@@ -47,16 +59,190 @@ func (h *NoteHandler) CreateNote(c *gin.Context) {
 	}
 
 	// Use the service to create the note
-	note, err := h.noteService.CreateNote(c.Request.Context(), userID, req.OriginalText)
-	if err != nil {
+	note, err := h.noteService.CreateNote(processingContext(c), userID, req.OriginalText)
+	if err != nil && note == nil {
+		if respondQuotaExceeded(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note"})
 		return
 	}
+	if err != nil {
+		// The note is saved, the reconciler queues it once the queue recovers
+		log.Printf("Failed to queue note %s: %v", note.ID, err)
+	}
 
 	// Return the note with pending status immediately
 	c.JSON(http.StatusAccepted, convertNoteToResponse(note))
 }
 
+// CreateNoteStream creates a note and processes it within the request,
+// relaying the LLM output as Server-Sent Events: "delta" events while it is
+// generated, then "done" with the saved note or "error"
+func (h *NoteHandler) CreateNoteStream(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userIDStr, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	// Parse request
+	var req dto.AddNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The stream starts with its first event, so a note rejected before
+	// processing can still be answered with a plain status
+	started := false
+	startStream := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+	}
+
+	note, err := h.noteService.CreateNoteStream(processingContext(c), userID, req.OriginalText, func(delta string) {
+		startStream()
+		c.SSEvent("delta", dto.NoteStreamDelta{Content: delta})
+		c.Writer.Flush()
+	})
+	if err != nil {
+		if !started && respondQuotaExceeded(c, err) {
+			return
+		}
+		startStream()
+		log.Printf("Failed to stream note for user %s: %v", userID, err)
+		event := dto.NoteStreamError{Error: "Failed to process note"}
+		if errors.Is(err, services.ErrNotFound) {
+			event.Error = "Note was deleted during processing"
+		} else if note != nil {
+			event.Error = "Failed to process note, it has been queued for processing"
+			event.NoteID = &note.ID
+		}
+		c.SSEvent("error", event)
+		c.Writer.Flush()
+		return
+	}
+
+	startStream()
+	c.SSEvent("done", convertNoteToResponse(note))
+	c.Writer.Flush()
+}
+
+// ImportNotes handles creating notes in bulk. Their processing is scheduled
+// behind notes created interactively.
+func (h *NoteHandler) ImportNotes(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userIDStr, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse UUID
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	// Parse request
+	var req dto.ImportNotesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	originalTexts := make([]string, len(req.Notes))
+	for i, note := range req.Notes {
+		originalTexts[i] = note.OriginalText
+	}
+
+	notes, err := h.noteService.ImportNotes(processingContext(c), userID, originalTexts)
+	if err != nil && len(notes) == 0 {
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import notes"})
+		return
+	}
+
+	response := dto.ImportNotesResponse{Notes: make([]dto.NoteResponse, len(notes))}
+	for i, note := range notes {
+		response.Notes[i] = convertNoteToResponse(note)
+	}
+
+	// Return the notes with pending status immediately
+	c.JSON(http.StatusAccepted, response)
+}
+
+// StreamNoteEvents streams status changes of the user's notes as
+// Server-Sent Events until the client disconnects
+func (h *NoteHandler) StreamNoteEvents(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userIDStr, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sub, err := h.noteService.SubscribeNoteEvents(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to subscribe to note events: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Note events are unavailable"})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			c.SSEvent("status", string(msg.Payload))
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
 // GetNote retrieves a specific note
 func (h *NoteHandler) GetNote(c *gin.Context) {
 	noteIDStr := c.Param("id")
@@ -163,6 +349,35 @@ func (h *NoteHandler) DeleteNote(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
 }
 
+// processingContext returns the request's context, set to bypass the LLM
+// response cache if the client asked for fresh results with ?cache=false
+func processingContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if c.Query("cache") == "false" {
+		ctx = ai.WithoutCache(ctx)
+	}
+	return ctx
+}
+
+// respondQuotaExceeded answers 429 if err is a quota violation, telling the
+// client when to retry and what is left of the user's quotas
+func respondQuotaExceeded(c *gin.Context, err error) bool {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(exceeded.RetryAfter.Seconds()))))
+	if exceeded.Remaining.Notes != quota.Unlimited {
+		c.Header("X-Quota-Notes-Remaining", strconv.FormatInt(exceeded.Remaining.Notes, 10))
+	}
+	if exceeded.Remaining.Tokens != quota.Unlimited {
+		c.Header("X-Quota-Tokens-Remaining", strconv.FormatInt(exceeded.Remaining.Tokens, 10))
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": exceeded.Error(), "quota": exceeded.Quota})
+	return true
+}
+
 // Helper function to convert Note model to NoteResponse DTO
 func convertNoteToResponse(note *models.Note) dto.NoteResponse {
 	tagNames := make([]string, len(note.Tags))
@@ -170,12 +385,25 @@ func convertNoteToResponse(note *models.Note) dto.NoteResponse {
 		tagNames[i] = tag.Name
 	}
 
+	// Notes that weren't processed, or came from the cache, didn't use any tokens
+	var usage *models.TokenUsage
+	if note.Usage.Model != "" {
+		usage = &note.Usage
+	}
+
 	return dto.NoteResponse{
 		ID:               note.ID,
 		OriginalText:     note.OriginalText,
 		GeneratedContent: note.GeneratedContent,
 		Status:           note.Status,
 		Tags:             tagNames,
+		Attempts:         note.Attempts,
+		NextRetryAt:      note.NextRetryAt,
+		Provider:         note.Provider,
+		PromptVersion:    note.PromptVersion,
+		InputFlags:       note.InputFlags,
+		Analysis:         note.Analysis,
+		Usage:            usage,
 		CreatedAt:        note.CreatedAt,
 	}
 }
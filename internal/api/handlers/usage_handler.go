@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultUsageDays is the range of days returned when none is given
+const defaultUsageDays = 30
+
+// UsageHandler handles queries of the user's LLM usage
+type UsageHandler struct {
+	usageService services.UsageService
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(usageService services.UsageService) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetUsage returns the authenticated user's token usage and cost per day and
+// model. ?from= and ?to= (YYYY-MM-DD, UTC, both included) default to the
+// last 30 days.
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -(defaultUsageDays - 1))
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		from = parsed
+	}
+
+	rows, err := h.usageService.GetDailyUsage(userID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUsageRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve usage"})
+		return
+	}
+
+	response := dto.UsageResponse{
+		From: from.Format(time.DateOnly),
+		To:   to.Format(time.DateOnly),
+		Days: make([]dto.UsageDayResponse, len(rows)),
+	}
+	for i, row := range rows {
+		response.Days[i] = dto.UsageDayResponse{
+			Date:             row.Day.Format(time.DateOnly),
+			Model:            row.Model,
+			Requests:         row.Requests,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			CostUSD:          row.CostUSD,
+		}
+		response.Total.Requests += row.Requests
+		response.Total.PromptTokens += row.PromptTokens
+		response.Total.CompletionTokens += row.CompletionTokens
+		response.Total.CostUSD += row.CostUSD
+	}
+	response.Total.TotalTokens = response.Total.PromptTokens + response.Total.CompletionTokens
+
+	c.JSON(http.StatusOK, response)
+}
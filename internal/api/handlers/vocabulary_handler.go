@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VocabularyHandler handles queries of the vocabulary collected from note analyses
+type VocabularyHandler struct {
+	vocabularyService services.VocabularyService
+}
+
+// NewVocabularyHandler creates a new VocabularyHandler
+func NewVocabularyHandler(vocabularyService services.VocabularyService) *VocabularyHandler {
+	return &VocabularyHandler{
+		vocabularyService: vocabularyService,
+	}
+}
+
+// GetVocabulary returns a page of the authenticated user's vocabulary, most
+// recently seen first. ?q= filters by lemma prefix, ?partOfSpeech= by part of speech.
+func (h *VocabularyHandler) GetVocabulary(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	items, total, err := h.vocabularyService.GetVocabulary(userID, c.Query("q"), c.Query("partOfSpeech"), offset, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPartOfSpeech) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve vocabulary"})
+		return
+	}
+
+	responseItems := make([]dto.VocabularyItemResponse, len(items))
+	for i, item := range items {
+		responseItems[i] = convertVocabularyItemToResponse(item)
+	}
+
+	c.JSON(http.StatusOK, dto.VocabularyListResponse{
+		Items:  responseItems,
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+	})
+}
+
+// GetNotesByLemma returns the authenticated user's notes containing a lemma
+func (h *VocabularyHandler) GetNotesByLemma(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	notes, err := h.vocabularyService.GetNotesByLemma(userID, c.Param("lemma"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes"})
+		return
+	}
+
+	responseNotes := make([]dto.NoteResponse, len(notes))
+	for i, note := range notes {
+		responseNotes[i] = convertNoteToResponse(note)
+	}
+
+	c.JSON(http.StatusOK, responseNotes)
+}
+
+// convertVocabularyItemToResponse converts a vocabulary item to a response DTO
+func convertVocabularyItemToResponse(item *models.VocabularyItem) dto.VocabularyItemResponse {
+	return dto.VocabularyItemResponse{
+		Lemma:        item.Lemma,
+		PartOfSpeech: item.PartOfSpeech,
+		Translation:  item.Translation,
+		NoteCount:    item.NoteCount,
+		LastSeenAt:   item.LastSeenAt,
+	}
+}
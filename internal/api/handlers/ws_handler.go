@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/api/middleware"
+	"ai-language-notes/internal/events"
+	"ai-language-notes/internal/services"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// wsPingInterval is how often the server pings an idle client
+	wsPingInterval = 30 * time.Second
+	// wsReadTimeout closes connections that sent nothing, not even a pong,
+	// for this long
+	wsReadTimeout = 2*wsPingInterval + 15*time.Second
+	// wsWriteTimeout bounds a single write to the client
+	wsWriteTimeout = 10 * time.Second
+
+	// wsTopicNotes covers status changes of all the user's notes
+	wsTopicNotes = "notes"
+	// wsTopicNotePrefix prefixes the topic of a single note ("notes:<id>")
+	wsTopicNotePrefix = "notes:"
+)
+
+// WebSocketHandler serves the multiplexed live update channel
+type WebSocketHandler struct {
+	noteService    services.NoteService
+	allowedOrigins []string
+}
+
+// NewWebSocketHandler creates a new WebSocketHandler. Browsers may only
+// connect from one of allowedOrigins, or from the API's own origin if it is
+// empty. "*" allows any origin.
+func NewWebSocketHandler(noteService services.NoteService, allowedOrigins []string) *WebSocketHandler {
+	return &WebSocketHandler{
+		noteService:    noteService,
+		allowedOrigins: allowedOrigins,
+	}
+}
+
+// Connect upgrades the request to a WebSocket. Clients subscribe to
+// "notes" for all their notes or "notes:<id>" for a single note, and must
+// answer pings with pongs.
+func (h *WebSocketHandler) Connect(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userIDStr, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	server := websocket.Server{
+		// CORS doesn't apply to WebSockets, so origins are checked here
+		Handshake: func(_ *websocket.Config, req *http.Request) error {
+			return h.checkOrigin(req)
+		},
+		Handler: func(conn *websocket.Conn) {
+			h.serve(conn, userID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin rejects handshakes from origins that aren't allowed. Clients
+// other than browsers don't send an Origin and are let through, the JWT
+// still has to be valid.
+func (h *WebSocketHandler) checkOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+
+	if len(h.allowedOrigins) == 0 {
+		parsed, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(parsed.Host, req.Host) {
+			return nil
+		}
+	}
+	for _, allowed := range h.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// serve runs a WebSocket session until either side closes it
+func (h *WebSocketHandler) serve(conn *websocket.Conn, userID uuid.UUID) {
+	defer conn.Close()
+
+	ctx := conn.Request().Context()
+	sub, err := h.noteService.SubscribeNoteEvents(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to subscribe to note events: %v", err)
+		h.send(conn, dto.WSServerMessage{Type: dto.WSTypeError, Error: "Note events are unavailable"})
+		return
+	}
+	defer sub.Close()
+
+	// Read client messages in the background
+	incoming := make(chan dto.WSClientMessage)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+			var msg dto.WSClientMessage
+			if err := websocket.JSON.Receive(conn, &msg); err != nil {
+				return
+			}
+			select {
+			case incoming <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	topics := make(map[string]bool)
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case msg := <-incoming:
+			err = h.handleClientMessage(conn, userID, topics, msg)
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			err = h.forward(conn, topics, msg)
+		case <-ping.C:
+			err = h.send(conn, dto.WSServerMessage{Type: dto.WSTypePing})
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleClientMessage applies a subscribe, unsubscribe, ping or pong message
+func (h *WebSocketHandler) handleClientMessage(conn *websocket.Conn, userID uuid.UUID, topics map[string]bool, msg dto.WSClientMessage) error {
+	switch msg.Type {
+	case dto.WSTypeSubscribe:
+		for _, topic := range msg.Topics {
+			if err := h.authorizeTopic(userID, topic); err != nil {
+				return h.send(conn, dto.WSServerMessage{Type: dto.WSTypeError, Topic: topic, Error: err.Error()})
+			}
+			topics[topic] = true
+		}
+		return h.send(conn, dto.WSServerMessage{Type: dto.WSTypeSubscribed, Topics: subscribedTopics(topics)})
+
+	case dto.WSTypeUnsubscribe:
+		for _, topic := range msg.Topics {
+			delete(topics, topic)
+		}
+		return h.send(conn, dto.WSServerMessage{Type: dto.WSTypeSubscribed, Topics: subscribedTopics(topics)})
+
+	case dto.WSTypePing:
+		return h.send(conn, dto.WSServerMessage{Type: dto.WSTypePong})
+
+	case dto.WSTypePong:
+		// Receiving it already extended the read deadline
+		return nil
+
+	default:
+		return h.send(conn, dto.WSServerMessage{Type: dto.WSTypeError, Error: "Unknown message type"})
+	}
+}
+
+// authorizeTopic checks that the user may subscribe to a topic
+func (h *WebSocketHandler) authorizeTopic(userID uuid.UUID, topic string) error {
+	if topic == wsTopicNotes {
+		return nil
+	}
+
+	if idStr, ok := strings.CutPrefix(topic, wsTopicNotePrefix); ok {
+		noteID, err := uuid.Parse(idStr)
+		if err != nil {
+			return services.ErrNotFound
+		}
+		_, err = h.noteService.GetNoteByID(noteID, userID)
+		return err
+	}
+
+	return services.ErrNotFound
+}
+
+// forward relays a note event to the client if it subscribed to it
+func (h *WebSocketHandler) forward(conn *websocket.Conn, topics map[string]bool, msg events.Message) error {
+	var event events.NoteEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		return nil
+	}
+
+	topic := wsTopicNotePrefix + event.NoteID.String()
+	if !topics[topic] {
+		if !topics[wsTopicNotes] {
+			return nil
+		}
+		topic = wsTopicNotes
+	}
+
+	return h.send(conn, dto.WSServerMessage{Type: dto.WSTypeEvent, Topic: topic, Data: msg.Payload})
+}
+
+// send writes a message to the client
+func (h *WebSocketHandler) send(conn *websocket.Conn, msg dto.WSServerMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return websocket.JSON.Send(conn, msg)
+}
+
+// subscribedTopics lists the topics of a subscription set
+func subscribedTopics(topics map[string]bool) []string {
+	list := make([]string, 0, len(topics))
+	for topic := range topics {
+		list = append(list, topic)
+	}
+	return list
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/api/middleware"
+	"ai-language-notes/internal/events"
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/services"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/net/websocket"
+)
+
+// newTestRedisBus connects a bus to the Redis server through its own client,
+// as a separate process would
+func newTestRedisBus(t *testing.T, server *miniredis.Miniredis) events.Bus {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return events.NewRedisBus(client)
+}
+
+// receiveWS reads the next server message, failing the test after a timeout
+func receiveWS(t *testing.T, conn *websocket.Conn) dto.WSServerMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg dto.WSServerMessage
+	if err := websocket.JSON.Receive(conn, &msg); err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketReceivesEventsPublishedByOtherInstances(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	apiBus := newTestRedisBus(t, server)
+	workerBus := newTestRedisBus(t, server)
+
+	userID := uuid.New()
+	noteService := services.NewNoteService(nil, nil, nil, nil, nil, nil, apiBus, nil)
+	handler := NewWebSocketHandler(noteService, nil)
+
+	router := gin.New()
+	router.GET("/ws", func(c *gin.Context) {
+		c.Set(middleware.UserIDKey, userID.String())
+	}, handler.Connect)
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/ws"
+	conn, err := websocket.Dial(wsURL, "", httpServer.URL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	if err := websocket.JSON.Send(conn, dto.WSClientMessage{Type: dto.WSTypeSubscribe, Topics: []string{wsTopicNotes}}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if msg := receiveWS(t, conn); msg.Type != dto.WSTypeSubscribed {
+		t.Fatalf("got %+v, want a subscribed message", msg)
+	}
+
+	// The worker publishes through its own connection to Redis
+	ctx := context.Background()
+	other := &models.Note{ID: uuid.New(), UserID: uuid.New(), Status: models.StatusCompleted}
+	if err := events.PublishNoteEvent(ctx, workerBus, other); err != nil {
+		t.Fatalf("PublishNoteEvent: %v", err)
+	}
+	note := &models.Note{ID: uuid.New(), UserID: userID, Status: models.StatusCompleted}
+	if err := events.PublishNoteEvent(ctx, workerBus, note); err != nil {
+		t.Fatalf("PublishNoteEvent: %v", err)
+	}
+
+	// Only the user's own note arrives
+	msg := receiveWS(t, conn)
+	if msg.Type != dto.WSTypeEvent || msg.Topic != wsTopicNotes {
+		t.Fatalf("got %+v, want an event on %q", msg, wsTopicNotes)
+	}
+	var event events.NoteEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		t.Fatalf("invalid event data %s: %v", msg.Data, err)
+	}
+	if event.NoteID != note.ID || event.Status != models.StatusCompleted {
+		t.Fatalf("got event %+v, want note %s completed", event, note.ID)
+	}
+}
@@ -14,6 +14,7 @@ const (
 	AuthorizationHeaderKey  = "Authorization"
 	AuthorizationTypeBearer = "Bearer"
 	UserIDKey               = "userID" // Key to store userID in Gin context
+	TokenQueryKey           = "token"  // Query parameter carrying the JWT where headers can't be set
 )
 
 // AuthMiddleware creates a Gin middleware for JWT authentication.
@@ -31,16 +32,34 @@ func AuthMiddleware(cfg config.Config) gin.HandlerFunc {
 			return
 		}
 
-		accessToken := fields[1]
-		claims, err := auth.ValidateToken(accessToken, cfg.JWTSecret)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()}) // Provide specific error from validation
+		authenticate(c, cfg, fields[1])
+	}
+}
+
+// WebSocketAuthMiddleware authenticates like AuthMiddleware but also accepts
+// the JWT in the token query parameter, since browsers can't set headers on
+// WebSocket handshakes.
+func WebSocketAuthMiddleware(cfg config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query(TokenQueryKey); token != "" {
+			authenticate(c, cfg, token)
 			return
 		}
+		AuthMiddleware(cfg)(c)
+	}
+}
 
-		// Set user ID in context for downstream handlers
-		c.Set(UserIDKey, claims.UserID.String()) // Store as string for easier retrieval
-
-		c.Next() // Proceed to the next handler
+// authenticate validates the access token and stores its user ID for
+// downstream handlers
+func authenticate(c *gin.Context, cfg config.Config, accessToken string) {
+	claims, err := auth.ValidateToken(accessToken, cfg.JWTSecret)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()}) // Provide specific error from validation
+		return
 	}
+
+	// Set user ID in context for downstream handlers
+	c.Set(UserIDKey, claims.UserID.String()) // Store as string for easier retrieval
+
+	c.Next() // Proceed to the next handler
 }
@@ -5,9 +5,12 @@ import (
 	"ai-language-notes/internal/api/handlers"
 	"ai-language-notes/internal/api/middleware"
 	"ai-language-notes/internal/config"
+	"ai-language-notes/internal/events"
 	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/quota"
 	"ai-language-notes/internal/repository"
 	"ai-language-notes/internal/services"
+	"ai-language-notes/internal/webhook"
 	"net/http"
 
 	"github.com/gin-contrib/cors"
@@ -19,12 +22,17 @@ func SetupRouter(
 	cfg config.Config,
 	userRepo repository.UserRepository,
 	noteRepo repository.NoteRepository,
+	webhookRepo repository.WebhookRepository,
+	usageRepo repository.UsageRepository,
+	quotaLimiter quota.Limiter,
 	llmService ai.LLMService,
-	queueService *queue.QueueService,
+	queueService queue.Queue,
+	bus events.Bus,
 ) *gin.Engine {
 
 	// gin.SetMode(gin.ReleaseMode) // Uncomment for production
-	r := gin.Default() // Includes Logger and Recovery middleware
+	r := gin.New()
+	r.Use(middleware.Logger(), gin.Recovery()) // The logger redacts tokens in query strings
 
 	// CORS Middleware Configuration
 	corsConfig := cors.DefaultConfig()
@@ -32,6 +40,7 @@ func SetupRouter(
 	// Or specify allowed origins: corsConfig.AllowOrigins = []string{"http://localhost:3000", "https://yourapp.com"}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"}
 	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	corsConfig.ExposeHeaders = []string{"Retry-After", "X-Quota-Notes-Remaining", "X-Quota-Tokens-Remaining"} // Sent with quota rejections
 	// corsConfig.AllowCredentials = true // Uncomment if using cookies/sessions with credentials
 	r.Use(cors.New(corsConfig))
 
@@ -57,30 +66,85 @@ func SetupRouter(
 	// --- User Routes ---
 	userService := services.NewUserService(userRepo)
 	userHandler := handlers.NewUserHandler(userService)
+	usageService := services.NewUsageService(usageRepo)
+	usageHandler := handlers.NewUsageHandler(usageService)
 	userRoutes := v1.Group("/user")
 	userRoutes.Use(authMiddleware) // Protect user routes
 	{
 		userRoutes.GET("/profile", userHandler.GetProfile)
 		userRoutes.PUT("/profile", userHandler.UpdateProfile)
+		userRoutes.GET("/usage", usageHandler.GetUsage)
 	}
 
+	// Only records deliveries, they are sent by the processes running the workers
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhook.Config{})
+
 	// --- Note Routes ---
 	noteService := services.NewNoteService(
 		noteRepo,
 		userRepo,
+		usageRepo,
+		quotaLimiter,
 		llmService,
 		queueService,
+		bus,
+		webhookDispatcher,
 	)
 	noteHandler := handlers.NewNoteHandler(noteService)
 	noteRoutes := v1.Group("/notes")
 	noteRoutes.Use(authMiddleware) // Protect note routes
 	{
 		noteRoutes.POST("", noteHandler.CreateNote)
+		noteRoutes.POST("/import", noteHandler.ImportNotes)
+		noteRoutes.POST("/stream", noteHandler.CreateNoteStream)
+		noteRoutes.GET("/events", noteHandler.StreamNoteEvents)
 		noteRoutes.GET("", noteHandler.GetUserNotes)
 		noteRoutes.GET("/:id", noteHandler.GetNote)
 		noteRoutes.DELETE("/:id", noteHandler.DeleteNote)
 	}
 
+	// --- Vocabulary Routes ---
+	vocabularyService := services.NewVocabularyService(noteRepo)
+	vocabularyHandler := handlers.NewVocabularyHandler(vocabularyService)
+	vocabularyRoutes := v1.Group("/vocabulary")
+	vocabularyRoutes.Use(authMiddleware) // Protect vocabulary routes
+	{
+		vocabularyRoutes.GET("", vocabularyHandler.GetVocabulary)
+		vocabularyRoutes.GET("/:lemma/notes", vocabularyHandler.GetNotesByLemma)
+	}
+
+	// --- Webhook Routes ---
+	webhookService := services.NewWebhookService(webhookRepo, cfg.WebhookAllowPrivateNetworks)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	webhookRoutes := v1.Group("/webhooks")
+	webhookRoutes.Use(authMiddleware) // Protect webhook routes
+	{
+		webhookRoutes.POST("", webhookHandler.CreateWebhook)
+		webhookRoutes.GET("", webhookHandler.GetWebhooks)
+		webhookRoutes.GET("/:id", webhookHandler.GetWebhook)
+		webhookRoutes.PUT("/:id", webhookHandler.UpdateWebhook)
+		webhookRoutes.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhookRoutes.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+	}
+
+	// --- Live Updates ---
+	wsHandler := handlers.NewWebSocketHandler(noteService, cfg.WebSocketOrigins())
+	v1.GET("/ws", middleware.WebSocketAuthMiddleware(cfg), wsHandler.Connect)
+
+	// --- Admin Routes ---
+	deadLetterService := services.NewDeadLetterService(queueService, noteRepo)
+	adminHandler := handlers.NewAdminHandler(deadLetterService, userService)
+	adminRoutes := v1.Group("/admin")
+	adminRoutes.Use(authMiddleware, middleware.AdminMiddleware(userRepo)) // Admins only
+	{
+		adminRoutes.GET("/dead-letters", adminHandler.ListDeadLetters)
+		adminRoutes.DELETE("/dead-letters", adminHandler.PurgeDeadLetters)
+		adminRoutes.GET("/dead-letters/:id", adminHandler.GetDeadLetter)
+		adminRoutes.POST("/dead-letters/:id/requeue", adminHandler.RequeueDeadLetter)
+		adminRoutes.DELETE("/dead-letters/:id", adminHandler.DeleteDeadLetter)
+		adminRoutes.PUT("/users/:id/plan", adminHandler.UpdateUserPlan)
+	}
+
 	// Handle Not Found routes
 	r.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Resource not found"})
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -34,13 +36,83 @@ type Config struct {
 	// LLM API settings
 	OpenAIAPIKey   string `mapstructure:"OPENAI_API_KEY"`
 	DeepSeekAPIKey string `mapstructure:"DEEPSEEK_API_KEY"`
-	LLMProvider    string `mapstructure:"LLM_PROVIDER"` // "openai" or "deepseek"
+	LLMProvider    string `mapstructure:"LLM_PROVIDER"` // "openai", "deepseek", "openai-compatible", "anthropic" or "gemini"
+
+	// Anthropic settings
+	AnthropicAPIKey  string `mapstructure:"ANTHROPIC_API_KEY"`
+	AnthropicBaseURL string `mapstructure:"ANTHROPIC_BASE_URL"`
+	AnthropicModel   string `mapstructure:"ANTHROPIC_MODEL"`
+	AnthropicVersion string `mapstructure:"ANTHROPIC_VERSION"` // value of the anthropic-version header
+
+	// Gemini settings
+	GeminiAPIKey  string `mapstructure:"GEMINI_API_KEY"`
+	GeminiBaseURL string `mapstructure:"GEMINI_BASE_URL"`
+	GeminiModel   string `mapstructure:"GEMINI_MODEL"`
+
+	// Overrides of the presets of the built-in providers
+	OpenAIBaseURL   string `mapstructure:"OPENAI_BASE_URL"`
+	OpenAIModel     string `mapstructure:"OPENAI_MODEL"`
+	DeepSeekBaseURL string `mapstructure:"DEEPSEEK_BASE_URL"`
+	DeepSeekModel   string `mapstructure:"DEEPSEEK_MODEL"`
+
+	// Generic OpenAI-compatible provider, e.g. a self-hosted vLLM, Ollama or LM Studio
+	CompatBaseURL        string `mapstructure:"OPENAI_COMPATIBLE_BASE_URL"`        // API root, e.g. http://localhost:11434/v1
+	CompatModel          string `mapstructure:"OPENAI_COMPATIBLE_MODEL"`           // model name passed to the server
+	CompatAPIKey         string `mapstructure:"OPENAI_COMPATIBLE_API_KEY"`         // optional for servers without auth
+	CompatAuthScheme     string `mapstructure:"OPENAI_COMPATIBLE_AUTH"`            // "bearer" (default), "header", "query" or "none"
+	CompatAuthHeader     string `mapstructure:"OPENAI_COMPATIBLE_AUTH_HEADER"`     // header carrying the key with the "header" scheme
+	CompatHeaders        string `mapstructure:"OPENAI_COMPATIBLE_HEADERS"`         // extra headers, "Name: value, Other: value"
+	CompatResponseFormat string `mapstructure:"OPENAI_COMPATIBLE_RESPONSE_FORMAT"` // "json_schema" (default), "json_object" or "none"
+
+	// LLM fallback settings
+	LLMProviders          string        `mapstructure:"LLM_PROVIDERS"`            // comma-separated providers tried in order, overrides LLM_PROVIDER
+	LLMBreakerFailures    int           `mapstructure:"LLM_BREAKER_FAILURES"`     // consecutive failures that take a provider out of rotation
+	LLMBreakerOpenTimeout time.Duration `mapstructure:"LLM_BREAKER_OPEN_TIMEOUT"` // how long a failing provider is skipped before it is probed again
+
+	// LLM cost accounting
+	LLMPrices string `mapstructure:"LLM_PRICES"` // USD per million tokens, "gpt-4o=2.5/10, openai-compatible/llama3.1=0/0"
+
+	// Prompt template settings
+	PromptsDir           string        `mapstructure:"PROMPTS_DIR"`             // templates added to or replacing the embedded ones
+	PromptVersions       string        `mapstructure:"PROMPT_VERSIONS"`         // pinned versions, "analysis=2, analysis.de=3"
+	PromptReloadInterval time.Duration `mapstructure:"PROMPTS_RELOAD_INTERVAL"` // how often PROMPTS_DIR is re-read, negative disables
 
 	// Feature flags
-	EnableCache bool `mapstructure:"ENABLE_CACHE"`
+	EnableCache bool          `mapstructure:"ENABLE_CACHE"`  // cache LLM responses in Redis
+	LLMCacheTTL time.Duration `mapstructure:"LLM_CACHE_TTL"` // how long cached LLM responses are served
+
+	// Quota settings
+	EnableQuotas bool   `mapstructure:"ENABLE_QUOTAS"` // enforce the plans' quotas, counted in Redis
+	PlanQuotas   string `mapstructure:"PLAN_QUOTAS"`   // limits per plan, "free=50/500000, pro=1000/20000000" (notes per day/tokens per month, 0 unlimited)
 
 	// Worker settings
-	WorkerCount int `mapstructure:"WORKER_COUNT"`
+	WorkerCount        int           `mapstructure:"WORKER_COUNT"`
+	EmbeddedWorkers    bool          `mapstructure:"EMBEDDED_WORKERS"`     // run the worker pool inside the API process
+	WorkerHTTPPort     string        `mapstructure:"WORKER_HTTP_PORT"`     // health and metrics port of cmd/worker
+	WorkerDrainTimeout time.Duration `mapstructure:"WORKER_DRAIN_TIMEOUT"` // how long in-flight tasks may finish on shutdown
+
+	// Queue settings
+	QueueBackend           string        `mapstructure:"QUEUE_BACKEND"`            // "redis", "postgres" or "memory"
+	QueueVisibilityTimeout time.Duration `mapstructure:"QUEUE_VISIBILITY_TIMEOUT"` // lease of a worker on its in-flight tasks
+	QueueReaperInterval    time.Duration `mapstructure:"QUEUE_REAPER_INTERVAL"`    // how often orphaned tasks are re-queued
+	QueueDedupTTL          time.Duration `mapstructure:"QUEUE_DEDUP_TTL"`          // how long a note's queued task blocks duplicate enqueues
+	TaskMaxAttempts        int           `mapstructure:"TASK_MAX_ATTEMPTS"`        // attempts before a note is marked failed and dead-lettered
+	RetryBaseDelay         time.Duration `mapstructure:"RETRY_BASE_DELAY"`         // backoff before the first retry, doubled per attempt
+	RetryMaxDelay          time.Duration `mapstructure:"RETRY_MAX_DELAY"`          // upper bound of the retry backoff
+
+	// Reconciler settings
+	ReconcileInterval   time.Duration `mapstructure:"RECONCILE_INTERVAL"`    // how often stuck notes are looked for
+	ReconcileStaleAfter time.Duration `mapstructure:"RECONCILE_STALE_AFTER"` // age after which a pending/processing note is re-enqueued
+
+	// Live update settings
+	WSAllowedOrigins string `mapstructure:"WS_ALLOWED_ORIGINS"` // origins allowed to open WebSockets, "https://app.example.com, ..." or "*"; same-origin only if empty
+
+	// Webhook settings
+	WebhookTimeout              time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`                // timeout of a single delivery request
+	WebhookMaxAttempts          int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`           // attempts before a delivery is marked failed
+	WebhookBaseDelay            time.Duration `mapstructure:"WEBHOOK_BASE_DELAY"`             // backoff before the first redelivery, doubled per attempt
+	WebhookMaxDelay             time.Duration `mapstructure:"WEBHOOK_MAX_DELAY"`              // upper bound of the redelivery backoff
+	WebhookAllowPrivateNetworks bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"` // allow webhook URLs on private, loopback and link-local addresses
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -65,8 +137,54 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetDefault("JWT_SECRET", "supersecretkey")
 	viper.SetDefault("JWT_EXPIRATION_HOURS", "72h")
 	viper.SetDefault("LLM_PROVIDER", "deepseek")
+	viper.SetDefault("LLM_PROVIDERS", "")
+	viper.SetDefault("OPENAI_BASE_URL", "")
+	viper.SetDefault("OPENAI_MODEL", "")
+	viper.SetDefault("DEEPSEEK_BASE_URL", "")
+	viper.SetDefault("DEEPSEEK_MODEL", "")
+	viper.SetDefault("ANTHROPIC_API_KEY", "")
+	viper.SetDefault("ANTHROPIC_BASE_URL", "")
+	viper.SetDefault("ANTHROPIC_MODEL", "")
+	viper.SetDefault("ANTHROPIC_VERSION", "2023-06-01")
+	viper.SetDefault("GEMINI_API_KEY", "")
+	viper.SetDefault("GEMINI_BASE_URL", "")
+	viper.SetDefault("GEMINI_MODEL", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_BASE_URL", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_MODEL", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_API_KEY", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_AUTH", "bearer")
+	viper.SetDefault("OPENAI_COMPATIBLE_AUTH_HEADER", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_HEADERS", "")
+	viper.SetDefault("OPENAI_COMPATIBLE_RESPONSE_FORMAT", "json_schema")
+	viper.SetDefault("LLM_BREAKER_FAILURES", 5)
+	viper.SetDefault("LLM_BREAKER_OPEN_TIMEOUT", "30s")
+	viper.SetDefault("LLM_PRICES", "")
+	viper.SetDefault("PROMPTS_DIR", "")
+	viper.SetDefault("PROMPT_VERSIONS", "")
+	viper.SetDefault("PROMPTS_RELOAD_INTERVAL", "30s")
 	viper.SetDefault("ENABLE_CACHE", true)
+	viper.SetDefault("LLM_CACHE_TTL", "168h")
+	viper.SetDefault("ENABLE_QUOTAS", true)
+	viper.SetDefault("PLAN_QUOTAS", "")
 	viper.SetDefault("WORKER_COUNT", 3)
+	viper.SetDefault("EMBEDDED_WORKERS", true)
+	viper.SetDefault("WORKER_HTTP_PORT", "9090")
+	viper.SetDefault("WORKER_DRAIN_TIMEOUT", "30s")
+	viper.SetDefault("QUEUE_BACKEND", "redis")
+	viper.SetDefault("QUEUE_VISIBILITY_TIMEOUT", "5m")
+	viper.SetDefault("QUEUE_REAPER_INTERVAL", "1m")
+	viper.SetDefault("QUEUE_DEDUP_TTL", "1h")
+	viper.SetDefault("TASK_MAX_ATTEMPTS", 5)
+	viper.SetDefault("RETRY_BASE_DELAY", "30s")
+	viper.SetDefault("RETRY_MAX_DELAY", "30m")
+	viper.SetDefault("RECONCILE_INTERVAL", "5m")
+	viper.SetDefault("RECONCILE_STALE_AFTER", "15m")
+	viper.SetDefault("WS_ALLOWED_ORIGINS", "")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_BASE_DELAY", "30s")
+	viper.SetDefault("WEBHOOK_MAX_DELAY", "1h")
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
 
 	err = viper.ReadInConfig()
 	// Ignore error if config file is not found, rely on env vars/defaults
@@ -88,6 +206,49 @@ func LoadConfig(path string) (config Config, err error) {
 	return
 }
 
+// LLMProviderChain returns the LLM providers to try, in order
+func (c *Config) LLMProviderChain() []string {
+	var providers []string
+	for _, name := range strings.Split(c.LLMProviders, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			providers = append(providers, name)
+		}
+	}
+	if len(providers) == 0 {
+		providers = []string{c.LLMProvider}
+	}
+	return providers
+}
+
+// WebSocketOrigins returns the origins allowed to open WebSockets
+func (c *Config) WebSocketOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.WSAllowedOrigins, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// PromptVersionPins parses PROMPT_VERSIONS into versions keyed by prompt
+// name or "<name>.<language>"
+func (c *Config) PromptVersionPins() (map[string]int, error) {
+	pins := make(map[string]int)
+	for _, pair := range strings.Split(c.PromptVersions, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(value), "v"))
+		if !ok || strings.TrimSpace(key) == "" || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid PROMPT_VERSIONS entry %q, expected <name>[.<language>]=<version>", strings.TrimSpace(pair))
+		}
+		pins[strings.ToLower(strings.TrimSpace(key))] = version
+	}
+	return pins, nil
+}
+
 // validateConfig performs validation of the configuration
 func validateConfig(cfg *Config) error {
 	// Check for critical configuration issues
@@ -96,12 +257,30 @@ func validateConfig(cfg *Config) error {
 	}
 
 	// Check LLM provider configuration
-	if cfg.LLMProvider == "openai" && cfg.OpenAIAPIKey == "" {
-		return fmt.Errorf("WARNING: OpenAI is selected as LLM_PROVIDER but OPENAI_API_KEY is not set")
+	for _, provider := range cfg.LLMProviderChain() {
+		if provider == "openai" && cfg.OpenAIAPIKey == "" {
+			return fmt.Errorf("WARNING: OpenAI is selected as LLM provider but OPENAI_API_KEY is not set")
+		}
+
+		if provider == "deepseek" && cfg.DeepSeekAPIKey == "" {
+			return fmt.Errorf("WARNING: DeepSeek is selected as LLM provider but DEEPSEEK_API_KEY is not set")
+		}
+
+		if provider == "anthropic" && cfg.AnthropicAPIKey == "" {
+			return fmt.Errorf("WARNING: Anthropic is selected as LLM provider but ANTHROPIC_API_KEY is not set")
+		}
+
+		if provider == "gemini" && cfg.GeminiAPIKey == "" {
+			return fmt.Errorf("WARNING: Gemini is selected as LLM provider but GEMINI_API_KEY is not set")
+		}
+
+		if provider == "openai-compatible" && (cfg.CompatBaseURL == "" || cfg.CompatModel == "") {
+			return fmt.Errorf("WARNING: openai-compatible is selected as LLM provider but OPENAI_COMPATIBLE_BASE_URL or OPENAI_COMPATIBLE_MODEL is not set")
+		}
 	}
 
-	if cfg.LLMProvider == "deepseek" && cfg.DeepSeekAPIKey == "" {
-		return fmt.Errorf("WARNING: DeepSeek is selected as LLM_PROVIDER but DEEPSEEK_API_KEY is not set")
+	if _, err := cfg.PromptVersionPins(); err != nil {
+		return err
 	}
 
 	// Database connection validation
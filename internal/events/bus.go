@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+)
+
+// Topics used across the application
+const (
+	// NoteCancelTopic carries IDs of notes whose processing must be aborted,
+	// e.g. because the note was deleted
+	NoteCancelTopic = "notes:cancel"
+)
+
+// subscriptionBuffer is how many undelivered messages a subscription holds
+// before further messages are dropped
+const subscriptionBuffer = 100
+
+// Message is a payload published on a topic
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Bus is a fire-and-forget publish/subscribe channel between the API and
+// worker processes. Messages published while nobody is subscribed are lost.
+type Bus interface {
+	// Publish sends a payload to all current subscribers of the topic
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe starts receiving messages published on the given topics
+	Subscribe(ctx context.Context, topics ...string) (Subscription, error)
+}
+
+// Subscription is a stream of messages from a Bus
+type Subscription interface {
+	// Messages returns the channel messages are delivered on. It is closed
+	// when the subscription is closed.
+	Messages() <-chan Message
+	// Close stops the subscription
+	Close() error
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBus implements Bus within a single process. Its messages never reach
+// other processes, so it is only meant for tests.
+type MemoryBus struct {
+	mu   sync.RWMutex
+	subs map[string]map[*memorySubscription]struct{}
+}
+
+// NewMemoryBus creates a new in-process bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[string]map[*memorySubscription]struct{})}
+}
+
+// Publish sends a payload to all subscribers of the topic. Subscribers
+// that fall behind miss the message rather than blocking the publisher.
+func (b *MemoryBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subs[topic] {
+		select {
+		case sub.messages <- Message{Topic: topic, Payload: payload}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe starts receiving messages published on the given topics
+func (b *MemoryBus) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	sub := &memorySubscription{
+		bus:      b,
+		topics:   topics,
+		messages: make(chan Message, subscriptionBuffer),
+	}
+
+	b.mu.Lock()
+	for _, topic := range topics {
+		if b.subs[topic] == nil {
+			b.subs[topic] = make(map[*memorySubscription]struct{})
+		}
+		b.subs[topic][sub] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return sub, nil
+}
+
+// memorySubscription is a subscription to a MemoryBus
+type memorySubscription struct {
+	bus       *MemoryBus
+	topics    []string
+	messages  chan Message
+	closeOnce sync.Once
+}
+
+// Messages returns the channel messages are delivered on
+func (s *memorySubscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Close stops the subscription
+func (s *memorySubscription) Close() error {
+	s.closeOnce.Do(func() {
+		s.bus.mu.Lock()
+		for _, topic := range s.topics {
+			delete(s.bus.subs[topic], s)
+			if len(s.bus.subs[topic]) == 0 {
+				delete(s.bus.subs, topic)
+			}
+		}
+		s.bus.mu.Unlock()
+		close(s.messages)
+	})
+	return nil
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"ai-language-notes/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// userNotesTopicPrefix prefixes the per-user topic of note status changes
+const userNotesTopicPrefix = "notes:user:"
+
+// NoteEvent reports a status transition of a note
+type NoteEvent struct {
+	NoteID       uuid.UUID               `json:"noteId"`
+	Status       models.ProcessingStatus `json:"status"`
+	ErrorMessage string                  `json:"errorMessage,omitempty"`
+	Attempts     int                     `json:"attempts"`
+	NextRetryAt  *time.Time              `json:"nextRetryAt,omitempty"`
+	UpdatedAt    time.Time               `json:"updatedAt"`
+}
+
+// UserNotesTopic returns the topic carrying status changes of a user's notes
+func UserNotesTopic(userID uuid.UUID) string {
+	return userNotesTopicPrefix + userID.String()
+}
+
+// PublishNoteEvent announces the current status of a note to its owner
+func PublishNoteEvent(ctx context.Context, bus Bus, note *models.Note) error {
+	payload, err := json.Marshal(NoteEvent{
+		NoteID:       note.ID,
+		Status:       note.Status,
+		ErrorMessage: note.ErrorMessage,
+		Attempts:     note.Attempts,
+		NextRetryAt:  note.NextRetryAt,
+		UpdatedAt:    note.UpdatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal note event: %w", err)
+	}
+
+	return bus.Publish(ctx, UserNotesTopic(note.UserID), payload)
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBus implements Bus with Redis pub/sub, so messages reach every
+// process connected to the same Redis
+type RedisBus struct {
+	redisClient *redis.Client
+}
+
+// NewRedisBus creates a new Redis-backed bus
+func NewRedisBus(redisClient *redis.Client) *RedisBus {
+	return &RedisBus{redisClient: redisClient}
+}
+
+// Publish sends a payload to all subscribers of the topic
+func (b *RedisBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	if err := b.redisClient.Publish(ctx, topic, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe starts receiving messages published on the given topics
+func (b *RedisBus) Subscribe(ctx context.Context, topics ...string) (Subscription, error) {
+	pubsub := b.redisClient.Subscribe(ctx, topics...)
+
+	// Wait for the confirmation so no message published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	sub := &redisSubscription{
+		pubsub:   pubsub,
+		messages: make(chan Message, subscriptionBuffer),
+		done:     make(chan struct{}),
+	}
+	go sub.forward()
+
+	return sub, nil
+}
+
+// redisSubscription adapts a go-redis PubSub to Subscription
+type redisSubscription struct {
+	pubsub    *redis.PubSub
+	messages  chan Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// forward copies messages from Redis until the subscription is closed
+func (s *redisSubscription) forward() {
+	defer close(s.messages)
+
+	for msg := range s.pubsub.Channel() {
+		select {
+		case s.messages <- Message{Topic: msg.Channel, Payload: []byte(msg.Payload)}:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Messages returns the channel messages are delivered on
+func (s *redisSubscription) Messages() <-chan Message {
+	return s.messages
+}
+
+// Close stops the subscription
+func (s *redisSubscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+	return err
+}
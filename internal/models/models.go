@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,10 +17,22 @@ type User struct {
 	PasswordHash   string    `gorm:"varchar(255);not null" json:"-"` // Never expose hash
 	NativeLanguage string    `gorm:"varchar(10);not null" json:"nativeLanguage"`
 	TargetLanguage string    `gorm:"varchar(10);not null" json:"targetLanguage"`
+	IsAdmin        bool      `gorm:"not null;default:false" json:"isAdmin"`
+	Plan           string    `gorm:"type:varchar(20);not null;default:'free'" json:"plan"` // sets the user's quotas
 	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
 	UpdatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updatedAt"`
 }
 
+// Plan tiers, each with its own quotas
+const (
+	PlanFree      = "free"
+	PlanPro       = "pro"
+	PlanUnlimited = "unlimited"
+)
+
+// Plans lists the plan tiers a user can be on
+var Plans = []string{PlanFree, PlanPro, PlanUnlimited}
+
 // Note represents a language learning note
 type Note struct {
 	ID               uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -27,11 +42,161 @@ type Note struct {
 	GeneratedContent string           `gorm:"type:text" json:"generatedContent,omitempty"`
 	Status           ProcessingStatus `gorm:"type:processing_status;not null;default:'pending'" json:"status"`
 	ErrorMessage     string           `gorm:"type:text" json:"errorMessage,omitempty"`
+	Attempts         int              `gorm:"not null;default:0" json:"attempts"`
+	NextRetryAt      *time.Time       `json:"nextRetryAt,omitempty"`
+	Provider         string           `gorm:"type:varchar(50)" json:"provider,omitempty"`
+	PromptVersion    string           `gorm:"type:varchar(100)" json:"promptVersion,omitempty"`
+	InputFlags       []string         `gorm:"type:jsonb;serializer:json" json:"inputFlags,omitempty"` // set on creation by ai.DetectInjection
+	Usage            TokenUsage       `gorm:"embedded" json:"usage"`
+	Analysis         *NoteAnalysis    `gorm:"type:jsonb" json:"analysis,omitempty"`
 	Tags             []Tag            `gorm:"many2many:note_tags;" json:"tags,omitempty"`
 	CreatedAt        time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
 	UpdatedAt        time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"updatedAt"`
 }
 
+// TokenUsage is what processing a note with an LLM consumed, summed over
+// all calls including repairs
+type TokenUsage struct {
+	Model            string  `gorm:"type:varchar(150)" json:"model,omitempty"` // "<provider>/<model>"
+	PromptTokens     int64   `gorm:"not null;default:0" json:"promptTokens"`
+	CompletionTokens int64   `gorm:"not null;default:0" json:"completionTokens"`
+	CostUSD          float64 `gorm:"type:numeric(14,6);not null;default:0" json:"costUsd"`
+}
+
+// Add returns the sum of two usages of the same model
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	if u.Model == "" {
+		u.Model = other.Model
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CostUSD += other.CostUSD
+	return u
+}
+
+// AddUsage adds a usage to the entry of its model, or appends it as the
+// model's entry. Usages of different models can't be summed since their
+// tokens are priced and reported per model. Empty usages are skipped.
+func AddUsage(usages []TokenUsage, usage TokenUsage) []TokenUsage {
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 && usage.CostUSD == 0 {
+		return usages
+	}
+	for i := range usages {
+		if usages[i].Model == usage.Model {
+			usages[i] = usages[i].Add(usage)
+			return usages
+		}
+	}
+	return append(usages, usage)
+}
+
+// UsageDaily aggregates a user's LLM usage per day and model
+type UsageDaily struct {
+	UserID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User             User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Day              time.Time `gorm:"type:date;primaryKey"`
+	Model            string    `gorm:"type:varchar(150);primaryKey"`
+	Requests         int64     `gorm:"not null;default:0"`
+	PromptTokens     int64     `gorm:"not null;default:0"`
+	CompletionTokens int64     `gorm:"not null;default:0"`
+	CostUSD          float64   `gorm:"type:numeric(14,6);not null;default:0"`
+	UpdatedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName keeps the table name singular like the migration
+func (UsageDaily) TableName() string {
+	return "usage_daily"
+}
+
+// NoteAnalysis is the structured analysis of a note's text
+type NoteAnalysis struct {
+	Vocabulary      []VocabularyEntry `json:"vocabulary"`
+	Grammar         []GrammarPoint    `json:"grammar"`
+	CulturalContext string            `json:"culturalContext"`
+	Alternatives    []Alternative     `json:"alternatives"`
+	Mistakes        []CommonMistake   `json:"mistakes"`
+}
+
+// VocabularyEntry is a word or expression of a note's text
+type VocabularyEntry struct {
+	Term         string `json:"term"`  // as it appears in the text
+	Lemma        string `json:"lemma"` // dictionary form
+	PartOfSpeech string `json:"partOfSpeech"`
+	Translation  string `json:"translation"` // in the learner's native language
+}
+
+// GrammarPoint explains a grammatical feature of a note's text
+type GrammarPoint struct {
+	Topic       string `json:"topic"`
+	Explanation string `json:"explanation"`
+	Example     string `json:"example"`
+}
+
+// Alternative is another way to express a note's text
+type Alternative struct {
+	Text        string `json:"text"`
+	Explanation string `json:"explanation"`
+}
+
+// CommonMistake is an error learners tend to make with a note's text
+type CommonMistake struct {
+	Mistake     string `json:"mistake"`
+	Correction  string `json:"correction"`
+	Explanation string `json:"explanation"`
+}
+
+// PartsOfSpeech lists the values of VocabularyEntry.PartOfSpeech
+var PartsOfSpeech = []string{
+	"noun", "verb", "adjective", "adverb", "pronoun", "preposition",
+	"conjunction", "determiner", "numeral", "particle", "interjection", "phrase", "other",
+}
+
+// Value implements driver.Valuer, storing the analysis as JSON. It is
+// implemented on the type rather than through a gorm serializer so it also
+// applies to map updates.
+func (a NoteAnalysis) Value() (driver.Value, error) {
+	return json.Marshal(a)
+}
+
+// Scan implements sql.Scanner
+func (a *NoteAnalysis) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for note analysis: %T", value)
+	}
+	return json.Unmarshal(data, a)
+}
+
+// NoteVocabularyEntry is a vocabulary entry of a note's analysis, kept in its
+// own table so vocabulary can be queried across notes. The lemma is stored
+// lower-cased to serve as lookup key.
+type NoteVocabularyEntry struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	NoteID       uuid.UUID `gorm:"type:uuid;not null;index"`
+	Note         Note      `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index:idx_note_vocabulary_entries_user_lemma"`
+	Term         string    `gorm:"type:text;not null"`
+	Lemma        string    `gorm:"type:text;not null;index:idx_note_vocabulary_entries_user_lemma"`
+	PartOfSpeech string    `gorm:"type:varchar(20);not null"`
+	Translation  string    `gorm:"type:text"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// VocabularyItem is a lemma of a user's vocabulary, aggregated across the
+// notes it appears in
+type VocabularyItem struct {
+	Lemma        string
+	PartOfSpeech string
+	Translation  string
+	NoteCount    int64
+	LastSeenAt   time.Time
+}
+
 // Tag represents a note tag
 type Tag struct {
 	ID    uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -48,3 +213,78 @@ const (
 	StatusCompleted  ProcessingStatus = "completed"
 	StatusFailed     ProcessingStatus = "failed"
 )
+
+// QueueTask is a processing task stored by the Postgres queue backend
+type QueueTask struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	NoteID      uuid.UUID      `gorm:"type:uuid;not null;index"`
+	UserID      uuid.UUID      `gorm:"type:uuid;not null;index:idx_queue_tasks_user_lane"`
+	Lane        string         `gorm:"type:varchar(20);not null;default:interactive;index:idx_queue_tasks_user_lane"`
+	UserSeq     int64          `gorm:"not null;default:0"` // position within the user's backlog in the lane
+	Payload     string         `gorm:"type:jsonb;not null"`
+	State       QueueTaskState `gorm:"type:varchar(20);not null;index:idx_queue_tasks_state_available"`
+	AvailableAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_queue_tasks_state_available"`
+	Attempts    int            `gorm:"not null;default:0"`
+	LockedBy    string         `gorm:"varchar(255)"`
+	LockedUntil *time.Time
+	LastError   string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// QueueTaskState is the lifecycle state of a QueueTask
+type QueueTaskState string
+
+const (
+	QueueTaskReady    QueueTaskState = "ready"
+	QueueTaskInFlight QueueTaskState = "inflight"
+	QueueTaskDead     QueueTaskState = "dead"
+)
+
+// Webhook is a user's subscription to note processing events
+type Webhook struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"userId"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	URL       string    `gorm:"type:text;not null" json:"url"`
+	Secret    string    `gorm:"varchar(255);not null" json:"-"` // HMAC key, only shown on creation
+	Events    []string  `gorm:"type:jsonb;serializer:json;not null" json:"events"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// Webhook event types
+const (
+	WebhookEventNoteCompleted = "note.completed"
+	WebhookEventNoteFailed    = "note.failed"
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to
+var WebhookEvents = []string{WebhookEventNoteCompleted, WebhookEventNoteFailed}
+
+// WebhookDelivery is one event sent (or to be sent) to a webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null;index" json:"webhookId"`
+	Webhook        Webhook               `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE" json:"-"`
+	EventType      string                `gorm:"varchar(50);not null" json:"eventType"`
+	Payload        string                `gorm:"type:jsonb;not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;index:idx_webhook_deliveries_due" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time             `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_webhook_deliveries_due" json:"nextAttemptAt"`
+	ResponseStatus int                   `json:"responseStatus,omitempty"`
+	LastError      string                `gorm:"type:text" json:"lastError,omitempty"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time             `gorm:"default:CURRENT_TIMESTAMP" json:"createdAt"`
+	UpdatedAt      time.Time             `gorm:"default:CURRENT_TIMESTAMP" json:"updatedAt"`
+}
+
+// WebhookDeliveryStatus is the state of a WebhookDelivery
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
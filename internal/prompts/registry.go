@@ -0,0 +1,362 @@
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Prompt names
+const (
+	// System sets up the model as a language learning assistant
+	System = "system"
+	// Analysis asks the model to analyze a learner's text
+	Analysis = "analysis"
+	// Repair asks the model to fix output that failed schema validation
+	Repair = "repair"
+)
+
+// requiredPrompts must each have a template for every language
+var requiredPrompts = []string{System, Analysis, Repair}
+
+// DefaultReloadInterval is used when templates are loaded from a directory
+// and no reload interval is configured
+const DefaultReloadInterval = 30 * time.Second
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// fileNamePattern matches template files: <name>.v<version>[.<language>].tmpl
+var fileNamePattern = regexp.MustCompile(`^([a-z_]+)\.v([0-9]+)(?:\.([a-z]{2,3}))?\.tmpl$`)
+
+// sampleData is rendered by every template on load so broken templates are
+// rejected before they are used
+var sampleData = Data{
+	Text:           "<sample learner text>",
+	NativeLanguage: "en",
+	TargetLanguage: "de",
+	PartsOfSpeech:  []string{"noun", "verb"},
+	Problems:       []string{"$: sample problem"},
+}
+
+// Data is passed to every template
+type Data struct {
+	// Text is the learner's text, delimited. Current templates don't use it:
+	// the text is sent as a separate message so it can't pass for
+	// instructions. It is kept for templates written before that, such as
+	// analysis v1.
+	Text           string
+	NativeLanguage string
+	TargetLanguage string
+	PartsOfSpeech  []string
+	// Problems are the validation errors of the output a repair is asked for
+	Problems []string
+}
+
+// Config holds the settings of a Registry
+type Config struct {
+	// Dir holds templates that are added to, or replace, the embedded ones.
+	// It is re-read every ReloadInterval.
+	Dir string
+	// ReloadInterval is how often Dir is re-read; negative disables reloading
+	ReloadInterval time.Duration
+	// Versions pins prompts to a version, keyed by prompt name or by
+	// "<name>.<language>" for a single target language. Unpinned prompts use
+	// their latest version.
+	Versions map[string]int
+}
+
+// Template is a version of a prompt, optionally specific to a target language
+type Template struct {
+	Name     string
+	Version  int
+	Language string // empty for templates used with any language
+	// Digest identifies the template's source, so edits without a version
+	// bump can still be told apart
+	Digest string
+	// InlinesText is set for templates that include the learner's text
+	// themselves instead of expecting it in a separate message
+	InlinesText bool
+
+	tmpl *template.Template
+}
+
+// ID identifies the template, e.g. "analysis/v2" or "analysis/v2/de"
+func (t *Template) ID() string {
+	id := fmt.Sprintf("%s/v%d", t.Name, t.Version)
+	if t.Language != "" {
+		id += "/" + t.Language
+	}
+	return id
+}
+
+// Render executes the template
+func (t *Template) Render(data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", t.ID(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Registry holds the prompt templates, loaded from the embedded defaults and
+// optionally a directory that is watched for changes
+type Registry struct {
+	config Config
+
+	mu        sync.RWMutex
+	templates []*Template
+	digest    string // of all templates, to detect changes on reload
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+var (
+	embeddedOnce     sync.Once
+	embeddedRegistry *Registry
+)
+
+// Embedded returns a registry of the embedded templates only. It is used by
+// LLM services that weren't given a registry.
+func Embedded() *Registry {
+	embeddedOnce.Do(func() {
+		registry, err := NewRegistry(Config{})
+		if err != nil {
+			panic(fmt.Sprintf("embedded prompt templates are invalid: %v", err))
+		}
+		embeddedRegistry = registry
+	})
+	return embeddedRegistry
+}
+
+// NewRegistry loads the prompt templates
+func NewRegistry(config Config) (*Registry, error) {
+	if config.Dir != "" && config.ReloadInterval == 0 {
+		config.ReloadInterval = DefaultReloadInterval
+	}
+
+	r := &Registry{
+		config: config,
+		stopCh: make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Start re-reads the template directory periodically, if one is configured
+func (r *Registry) Start() {
+	if r.config.Dir == "" || r.config.ReloadInterval <= 0 {
+		return
+	}
+
+	r.wg.Add(1)
+	go r.loop()
+	log.Printf("Watching prompt templates in %s", r.config.Dir)
+}
+
+// Stop stops watching the template directory
+func (r *Registry) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+}
+
+// loop reloads the templates until stopped
+func (r *Registry) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				log.Printf("Failed to reload prompt templates, keeping the previous ones: %v", err)
+			}
+		}
+	}
+}
+
+// Reload reads the templates again. If any of them is invalid the
+// previously loaded templates are kept.
+func (r *Registry) Reload() error {
+	byID := make(map[string]*Template)
+	if err := loadTemplates(embeddedTemplates, "templates", byID); err != nil {
+		return err
+	}
+	if r.config.Dir != "" {
+		if err := loadTemplates(os.DirFS(r.config.Dir), ".", byID); err != nil {
+			return err
+		}
+	}
+
+	templates := make([]*Template, 0, len(byID))
+	for _, t := range byID {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID() < templates[j].ID() })
+
+	hash := sha256.New()
+	for _, t := range templates {
+		hash.Write([]byte(t.ID() + ":" + t.Digest + "\n"))
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	// Every language must be able to resolve the required prompts
+	for _, name := range requiredPrompts {
+		if _, err := resolve(templates, r.config.Versions, name, ""); err != nil {
+			return err
+		}
+	}
+	for key, version := range r.config.Versions {
+		name, language, _ := strings.Cut(key, ".")
+		if _, err := resolve(templates, map[string]int{key: version}, name, language); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.digest != "" && r.digest != digest {
+		log.Printf("Reloaded prompt templates")
+	}
+	r.templates = templates
+	r.digest = digest
+	return nil
+}
+
+// Resolve returns the template of a prompt to use for a target language: the
+// pinned or else latest version, preferring a template specific to the
+// language over the generic one
+func (r *Registry) Resolve(name, language string) (*Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return resolve(r.templates, r.config.Versions, name, normalizeLanguage(language))
+}
+
+// Fingerprint identifies the templates of the required prompts used for a
+// target language, so responses can be cached per prompt
+func (r *Registry) Fingerprint(language string) (string, error) {
+	hash := sha256.New()
+	for _, name := range requiredPrompts {
+		t, err := r.Resolve(name, language)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(t.ID() + ":" + t.Digest + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Templates lists the loaded templates ordered by ID
+func (r *Registry) Templates() []*Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Template(nil), r.templates...)
+}
+
+// resolve picks a template among the loaded ones
+func resolve(templates []*Template, versions map[string]int, name, language string) (*Template, error) {
+	pinned, isPinned := versions[name+"."+language]
+	if !isPinned {
+		pinned, isPinned = versions[name]
+	}
+
+	var best *Template
+	for _, t := range templates {
+		if t.Name != name || (t.Language != "" && t.Language != language) {
+			continue
+		}
+		if isPinned && t.Version != pinned {
+			continue
+		}
+		if best == nil || t.Version > best.Version ||
+			(t.Version == best.Version && t.Language != "") {
+			best = t
+		}
+	}
+
+	if best == nil {
+		if isPinned {
+			return nil, fmt.Errorf("no template for prompt %s version %d (language %q)", name, pinned, language)
+		}
+		return nil, fmt.Errorf("no template for prompt %s (language %q)", name, language)
+	}
+	return best, nil
+}
+
+// loadTemplates parses the template files of a directory into byID,
+// replacing templates with the same ID
+func loadTemplates(fsys fs.FS, dir string, byID map[string]*Template) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt templates: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmpl") {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return fmt.Errorf("invalid prompt template name %q, expected <name>.v<version>[.<language>].tmpl", entry.Name())
+		}
+		version, err := strconv.Atoi(match[2])
+		if err != nil {
+			return fmt.Errorf("invalid version in prompt template name %q: %w", entry.Name(), err)
+		}
+
+		source, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read prompt template %s: %w", entry.Name(), err)
+		}
+
+		tmpl, err := template.New(entry.Name()).
+			Option("missingkey=error").
+			Funcs(template.FuncMap{"join": strings.Join}).
+			Parse(string(source))
+		if err != nil {
+			return fmt.Errorf("failed to parse prompt template %s: %w", entry.Name(), err)
+		}
+
+		sum := sha256.Sum256(source)
+		t := &Template{
+			Name:     match[1],
+			Version:  version,
+			Language: match[3],
+			Digest:   hex.EncodeToString(sum[:]),
+			tmpl:     tmpl,
+		}
+		sample, err := t.Render(sampleData)
+		if err != nil {
+			return err
+		}
+		t.InlinesText = strings.Contains(sample, sampleData.Text)
+		byID[t.ID()] = t
+	}
+	return nil
+}
+
+// normalizeLanguage lower-cases a language code
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
@@ -0,0 +1,47 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveLatestAnalysis(t *testing.T) {
+	registry, err := NewRegistry(Config{})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	analysis, err := registry.Resolve(Analysis, "de")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if analysis.ID() != "analysis/v2" || analysis.InlinesText {
+		t.Fatalf("got %s (inlines text: %v), want analysis/v2 without the text", analysis.ID(), analysis.InlinesText)
+	}
+}
+
+func TestResolvePinnedAnalysisV1(t *testing.T) {
+	registry, err := NewRegistry(Config{Versions: map[string]int{Analysis: 1}})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	analysis, err := registry.Resolve(Analysis, "de")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if analysis.ID() != "analysis/v1" || !analysis.InlinesText {
+		t.Fatalf("got %s (inlines text: %v), want analysis/v1 with the text", analysis.ID(), analysis.InlinesText)
+	}
+
+	rendered, err := analysis.Render(Data{
+		Text:           "<learner_text>\nHallo Welt\n</learner_text>",
+		NativeLanguage: "English",
+		TargetLanguage: "German",
+		PartsOfSpeech:  []string{"noun"},
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(rendered, "Hallo Welt") {
+		t.Fatalf("rendered prompt doesn't include the text:\n%s", rendered)
+	}
+}
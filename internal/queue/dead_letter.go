@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeadLetter is a task that was given up on, kept for inspection
+type DeadLetter struct {
+	ID       uuid.UUID `json:"id"`
+	Payload  string    `json:"payload"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	WorkerID string    `json:"worker_id"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Task decodes the dead-lettered payload back into a task
+func (d *DeadLetter) Task() (*LLMProcessingTask, error) {
+	var task LLMProcessingTask
+	if err := json.Unmarshal([]byte(d.Payload), &task); err != nil {
+		return nil, ErrInvalidPayload
+	}
+	return &task, nil
+}
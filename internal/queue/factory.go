@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// BackendType represents the storage behind a Queue
+type BackendType string
+
+const (
+	BackendRedis    BackendType = "redis"
+	BackendMemory   BackendType = "memory"
+	BackendPostgres BackendType = "postgres"
+)
+
+// Config contains configuration for creating a Queue
+type Config struct {
+	Backend           BackendType
+	RedisClient       *redis.Client // required by the redis backend
+	DB                *gorm.DB      // required by the postgres backend
+	VisibilityTimeout time.Duration
+	DedupTTL          time.Duration // only used by the redis backend
+}
+
+// NewQueue creates a Queue for the configured backend
+func NewQueue(config Config) (Queue, error) {
+	switch config.Backend {
+	case BackendRedis:
+		if config.RedisClient == nil {
+			return nil, fmt.Errorf("redis queue backend requires a Redis client")
+		}
+		return NewRedisQueue(config.RedisClient, config.VisibilityTimeout, config.DedupTTL), nil
+
+	case BackendMemory:
+		return NewMemoryQueue(DefaultMemoryQueueCapacity, config.VisibilityTimeout), nil
+
+	case BackendPostgres:
+		if config.DB == nil {
+			return nil, fmt.Errorf("postgres queue backend requires a database connection")
+		}
+		return NewPostgresQueue(config.DB, config.VisibilityTimeout), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported queue backend: %s", config.Backend)
+	}
+}
@@ -0,0 +1,434 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultMemoryQueueCapacity bounds the number of ready tasks of a MemoryQueue
+const DefaultMemoryQueueCapacity = 10000
+
+// memoryLease is a task leased to a consumer
+type memoryLease struct {
+	consumerID string
+	task       LLMProcessingTask
+}
+
+// memoryLane holds the ready tasks of one priority lane, one FIFO per user
+// and a ring of users that have tasks queued
+type memoryLane struct {
+	users  []uuid.UUID
+	queues map[uuid.UUID][]LLMProcessingTask
+}
+
+// memoryDelayed is a task waiting for its retry
+type memoryDelayed struct {
+	dueAt time.Time
+	task  LLMProcessingTask
+}
+
+// MemoryQueue implements Queue in process memory. Tasks don't survive a
+// restart, so it is meant for tests and single-binary deployments.
+type MemoryQueue struct {
+	capacity          int
+	visibilityTimeout time.Duration
+
+	mu          sync.Mutex
+	lanes       map[Priority]*memoryLane
+	size        int
+	notify      chan struct{}
+	dequeues    uint64
+	nextReceipt uint64
+	inFlight    map[string]*memoryLease
+	queued      map[uuid.UUID]struct{} // notes with a task queued or in flight
+	heartbeats  map[string]time.Time
+	delayed     []memoryDelayed
+	deadLetters map[uuid.UUID]*DeadLetter
+}
+
+// NewMemoryQueue creates a new in-process queue holding up to capacity ready tasks
+func NewMemoryQueue(capacity int, visibilityTimeout time.Duration) *MemoryQueue {
+	if capacity <= 0 {
+		capacity = DefaultMemoryQueueCapacity
+	}
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = DefaultVisibilityTimeout
+	}
+
+	lanes := make(map[Priority]*memoryLane, len(Priorities))
+	for _, priority := range Priorities {
+		lanes[priority] = &memoryLane{queues: make(map[uuid.UUID][]LLMProcessingTask)}
+	}
+
+	return &MemoryQueue{
+		capacity:          capacity,
+		visibilityTimeout: visibilityTimeout,
+		lanes:             lanes,
+		notify:            make(chan struct{}),
+		inFlight:          make(map[string]*memoryLease),
+		queued:            make(map[uuid.UUID]struct{}),
+		heartbeats:        make(map[string]time.Time),
+		deadLetters:       make(map[uuid.UUID]*DeadLetter),
+	}
+}
+
+// EnqueueTask adds a task to its user's queue in the task's lane, unless a
+// task for the same note is already queued or in flight
+func (q *MemoryQueue) EnqueueTask(ctx context.Context, task *LLMProcessingTask) (bool, error) {
+	q.mu.Lock()
+	if _, ok := q.queued[task.NoteID]; ok {
+		q.mu.Unlock()
+		return false, nil
+	}
+	q.queued[task.NoteID] = struct{}{}
+	q.mu.Unlock()
+
+	if err := q.push(*task); err != nil {
+		q.forget(task.NoteID)
+		return false, err
+	}
+	return true, nil
+}
+
+// DequeueTask blocks until a task is available or ctx is done
+func (q *MemoryQueue) DequeueTask(ctx context.Context, consumerID string) (*LLMProcessingTask, error) {
+	if err := q.Heartbeat(ctx, consumerID); err != nil {
+		return nil, err
+	}
+
+	for {
+		q.mu.Lock()
+		task, ok := q.pop()
+		if ok {
+			q.nextReceipt++
+			task.receipt = strconv.FormatUint(q.nextReceipt, 10)
+			q.inFlight[task.receipt] = &memoryLease{consumerID: consumerID, task: task}
+			q.mu.Unlock()
+
+			return &task, nil
+		}
+		wait := q.notify
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to dequeue task: %w", ctx.Err())
+		case <-wait:
+		}
+	}
+}
+
+// AckTask releases a finished task
+func (q *MemoryQueue) AckTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	if lease := q.release(task); lease != nil {
+		q.forget(lease.task.NoteID)
+	}
+	return nil
+}
+
+// NackTask puts a leased task back onto the queue with its attempt counter incremented
+func (q *MemoryQueue) NackTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	lease := q.release(task)
+	if lease == nil {
+		return nil
+	}
+
+	lease.task.Attempts++
+	lease.task.Redelivered = true
+	return q.push(lease.task)
+}
+
+// ReleaseTask puts a leased task back onto the queue without counting the attempt
+func (q *MemoryQueue) ReleaseTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	lease := q.release(task)
+	if lease == nil {
+		return nil
+	}
+
+	lease.task.Redelivered = true
+	return q.push(lease.task)
+}
+
+// ScheduleRetry parks a leased task until dueAt, with its attempt counter incremented
+func (q *MemoryQueue) ScheduleRetry(ctx context.Context, consumerID string, task *LLMProcessingTask, dueAt time.Time) error {
+	lease := q.release(task)
+	if lease == nil {
+		return nil
+	}
+
+	lease.task.Attempts++
+
+	q.mu.Lock()
+	q.delayed = append(q.delayed, memoryDelayed{dueAt: dueAt, task: lease.task})
+	q.mu.Unlock()
+
+	return nil
+}
+
+// DeadLetterTask moves a leased task into the dead-letter store
+func (q *MemoryQueue) DeadLetterTask(ctx context.Context, consumerID string, task *LLMProcessingTask, cause error) error {
+	lease := q.release(task)
+	if lease == nil {
+		return nil
+	}
+	q.forget(lease.task.NoteID)
+
+	payload, err := json.Marshal(&lease.task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	entry := &DeadLetter{
+		ID:       uuid.New(),
+		Payload:  string(payload),
+		Attempts: lease.task.Attempts + 1,
+		WorkerID: consumerID,
+		FailedAt: time.Now(),
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	q.mu.Lock()
+	q.deadLetters[entry.ID] = entry
+	q.mu.Unlock()
+
+	return nil
+}
+
+// Heartbeat renews the consumer's lease for another visibility timeout
+func (q *MemoryQueue) Heartbeat(ctx context.Context, consumerID string) error {
+	q.mu.Lock()
+	q.heartbeats[consumerID] = time.Now().Add(q.visibilityTimeout)
+	q.mu.Unlock()
+	return nil
+}
+
+// RequeueOrphanedTasks puts tasks of consumers whose lease expired back onto the queue
+func (q *MemoryQueue) RequeueOrphanedTasks(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	var orphans []LLMProcessingTask
+	for receipt, lease := range q.inFlight {
+		if q.heartbeats[lease.consumerID].After(now) {
+			continue
+		}
+		lease.task.Redelivered = true
+		orphans = append(orphans, lease.task)
+		delete(q.inFlight, receipt)
+	}
+	q.mu.Unlock()
+
+	return q.pushAll(orphans)
+}
+
+// PromoteDueTasks puts scheduled retries whose due time passed back onto the queue
+func (q *MemoryQueue) PromoteDueTasks(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	var due []LLMProcessingTask
+	waiting := q.delayed[:0]
+	for _, entry := range q.delayed {
+		if entry.dueAt.After(now) {
+			waiting = append(waiting, entry)
+			continue
+		}
+		due = append(due, entry.task)
+	}
+	q.delayed = waiting
+	q.mu.Unlock()
+
+	return q.pushAll(due)
+}
+
+// ListDeadLetters returns a page of dead letters, newest first
+func (q *MemoryQueue) ListDeadLetters(ctx context.Context, offset, limit int) ([]*DeadLetter, int64, error) {
+	q.mu.Lock()
+	entries := make([]*DeadLetter, 0, len(q.deadLetters))
+	for _, entry := range q.deadLetters {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	q.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FailedAt.After(entries[j].FailedAt)
+	})
+
+	total := int64(len(entries))
+	if offset >= len(entries) {
+		return []*DeadLetter{}, total, nil
+	}
+	end := offset + limit
+	if end > len(entries) {
+		end = len(entries)
+	}
+
+	return entries[offset:end], total, nil
+}
+
+// GetDeadLetter returns a single dead letter by ID
+func (q *MemoryQueue) GetDeadLetter(ctx context.Context, id uuid.UUID) (*DeadLetter, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.deadLetters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+// RequeueDeadLetter puts a dead-lettered task back onto the queue with a
+// fresh attempt counter, unless a task for the note is already queued or in flight
+func (q *MemoryQueue) RequeueDeadLetter(ctx context.Context, id uuid.UUID) (*LLMProcessingTask, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entry, ok := q.deadLetters[id]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+	task, err := entry.Task()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := q.queued[task.NoteID]; ok {
+		return nil, ErrTaskQueued
+	}
+	task.Attempts = 0
+
+	if err := q.pushLocked(*task); err != nil {
+		return nil, err
+	}
+	delete(q.deadLetters, id)
+	q.queued[task.NoteID] = struct{}{}
+
+	return task, nil
+}
+
+// DeleteDeadLetter permanently removes a single dead letter
+func (q *MemoryQueue) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.deadLetters[id]; !ok {
+		return ErrDeadLetterNotFound
+	}
+	delete(q.deadLetters, id)
+	return nil
+}
+
+// PurgeDeadLetters removes all dead letters
+func (q *MemoryQueue) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	purged := int64(len(q.deadLetters))
+	q.deadLetters = make(map[uuid.UUID]*DeadLetter)
+	return purged, nil
+}
+
+// release removes a task from the in-flight set and returns its lease
+func (q *MemoryQueue) release(task *LLMProcessingTask) *memoryLease {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lease, ok := q.inFlight[task.receipt]
+	if !ok {
+		return nil
+	}
+	delete(q.inFlight, task.receipt)
+	lease.task.receipt = ""
+	return lease
+}
+
+// forget clears the dedup marker of a note once its task is settled
+func (q *MemoryQueue) forget(noteID uuid.UUID) {
+	q.mu.Lock()
+	delete(q.queued, noteID)
+	q.mu.Unlock()
+}
+
+// push adds a task to its user's FIFO in its lane and wakes waiting consumers
+func (q *MemoryQueue) push(task LLMProcessingTask) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pushLocked(task)
+}
+
+// pushLocked is push for callers that already hold q.mu
+func (q *MemoryQueue) pushLocked(task LLMProcessingTask) error {
+	if q.size >= q.capacity {
+		return fmt.Errorf("failed to enqueue task: %w", ErrQueueFull)
+	}
+
+	lane := q.lanes[task.lane()]
+	if len(lane.queues[task.UserID]) == 0 {
+		lane.users = append(lane.users, task.UserID)
+	}
+	lane.queues[task.UserID] = append(lane.queues[task.UserID], task)
+	q.size++
+
+	close(q.notify)
+	q.notify = make(chan struct{})
+	return nil
+}
+
+// pop takes the next task following the lane schedule, rotating through the
+// users of a lane. The caller must hold q.mu.
+func (q *MemoryQueue) pop() (LLMProcessingTask, bool) {
+	if q.size == 0 {
+		return LLMProcessingTask{}, false
+	}
+
+	q.dequeues++
+	for _, priority := range laneOrder(q.dequeues) {
+		lane := q.lanes[priority]
+		if len(lane.users) == 0 {
+			continue
+		}
+
+		userID := lane.users[0]
+		lane.users = lane.users[1:]
+
+		tasks := lane.queues[userID]
+		task := tasks[0]
+		if len(tasks) > 1 {
+			lane.queues[userID] = tasks[1:]
+			lane.users = append(lane.users, userID)
+		} else {
+			delete(lane.queues, userID)
+		}
+		q.size--
+
+		return task, true
+	}
+	return LLMProcessingTask{}, false
+}
+
+// pushAll adds tasks to their lanes and returns how many were added.
+// Tasks that don't fit are parked as due retries so they aren't lost.
+func (q *MemoryQueue) pushAll(tasks []LLMProcessingTask) (int, error) {
+	for i, task := range tasks {
+		if err := q.push(task); err != nil {
+			q.mu.Lock()
+			for _, rest := range tasks[i:] {
+				q.delayed = append(q.delayed, memoryDelayed{dueAt: time.Now(), task: rest})
+			}
+			q.mu.Unlock()
+			return i, err
+		}
+	}
+	return len(tasks), nil
+}
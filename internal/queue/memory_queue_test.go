@@ -0,0 +1,254 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestTask() *LLMProcessingTask {
+	return &LLMProcessingTask{
+		NoteID:         uuid.New(),
+		UserID:         uuid.New(),
+		OriginalText:   "Hola mundo",
+		NativeLanguage: "English",
+		TargetLanguage: "Spanish",
+		CreatedAt:      time.Now(),
+	}
+}
+
+// dequeue takes the next task or fails the test if none arrives in time
+func dequeue(t *testing.T, q Queue, consumerID string) *LLMProcessingTask {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	task, err := q.DequeueTask(ctx, consumerID)
+	if err != nil {
+		t.Fatalf("DequeueTask: %v", err)
+	}
+	return task
+}
+
+// assertEmpty fails the test if a task can be dequeued
+func assertEmpty(t *testing.T, q Queue) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if task, err := q.DequeueTask(ctx, "probe"); err == nil {
+		t.Fatalf("expected an empty queue, got note %s", task.NoteID)
+	}
+}
+
+func TestMemoryQueueEnqueueDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+	task := newTestTask()
+
+	for i, want := range []bool{true, false} {
+		added, err := q.EnqueueTask(ctx, task)
+		if err != nil {
+			t.Fatalf("EnqueueTask #%d: %v", i+1, err)
+		}
+		if added != want {
+			t.Fatalf("EnqueueTask #%d added = %v, want %v", i+1, added, want)
+		}
+	}
+
+	got := dequeue(t, q, "worker-1")
+	if got.NoteID != task.NoteID {
+		t.Fatalf("got note %s, want %s", got.NoteID, task.NoteID)
+	}
+	assertEmpty(t, q)
+
+	// Still deduplicated while in flight
+	if added, err := q.EnqueueTask(ctx, task); err != nil || added {
+		t.Fatalf("EnqueueTask while in flight = %v, %v; want false, nil", added, err)
+	}
+	assertEmpty(t, q)
+}
+
+func TestMemoryQueueAckReleasesNote(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	got := dequeue(t, q, "worker-1")
+	if err := q.AckTask(ctx, "worker-1", got); err != nil {
+		t.Fatalf("AckTask: %v", err)
+	}
+
+	// The note can be queued again once its task is done
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask after ack: %v", err)
+	}
+	if got := dequeue(t, q, "worker-1"); got.NoteID != task.NoteID {
+		t.Fatalf("got note %s, want %s", got.NoteID, task.NoteID)
+	}
+}
+
+func TestMemoryQueueNackRedelivers(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+
+	if _, err := q.EnqueueTask(ctx, newTestTask()); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.NackTask(ctx, "worker-1", dequeue(t, q, "worker-1")); err != nil {
+		t.Fatalf("NackTask: %v", err)
+	}
+
+	got := dequeue(t, q, "worker-2")
+	if got.Attempts != 1 || !got.Redelivered {
+		t.Fatalf("got attempts=%d redelivered=%v, want 1 and true", got.Attempts, got.Redelivered)
+	}
+}
+
+func TestMemoryQueueScheduleRetry(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.ScheduleRetry(ctx, "worker-1", dequeue(t, q, "worker-1"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ScheduleRetry: %v", err)
+	}
+
+	// Not due yet, and the note stays deduplicated while it waits
+	if n, err := q.PromoteDueTasks(ctx); err != nil || n != 0 {
+		t.Fatalf("PromoteDueTasks = %d, %v; want 0, nil", n, err)
+	}
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask while delayed: %v", err)
+	}
+	assertEmpty(t, q)
+
+	q.mu.Lock()
+	q.delayed[0].dueAt = time.Now().Add(-time.Second)
+	q.mu.Unlock()
+
+	if n, err := q.PromoteDueTasks(ctx); err != nil || n != 1 {
+		t.Fatalf("PromoteDueTasks = %d, %v; want 1, nil", n, err)
+	}
+	got := dequeue(t, q, "worker-1")
+	if got.NoteID != task.NoteID || got.Attempts != 1 {
+		t.Fatalf("got note %s with %d attempts, want %s with 1", got.NoteID, got.Attempts, task.NoteID)
+	}
+}
+
+func TestMemoryQueueRequeuesOrphanedTasks(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, 10*time.Millisecond)
+
+	if _, err := q.EnqueueTask(ctx, newTestTask()); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	dequeue(t, q, "worker-1")
+	time.Sleep(20 * time.Millisecond)
+
+	if n, err := q.RequeueOrphanedTasks(ctx); err != nil || n != 1 {
+		t.Fatalf("RequeueOrphanedTasks = %d, %v; want 1, nil", n, err)
+	}
+	if got := dequeue(t, q, "worker-2"); !got.Redelivered {
+		t.Fatal("expected the orphaned task to be flagged as redelivered")
+	}
+}
+
+func TestMemoryQueueDeadLetterAndRequeue(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.DeadLetterTask(ctx, "worker-1", dequeue(t, q, "worker-1"), errors.New("provider down")); err != nil {
+		t.Fatalf("DeadLetterTask: %v", err)
+	}
+
+	entries, total, err := q.ListDeadLetters(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if total != 1 || len(entries) != 1 {
+		t.Fatalf("got %d dead letters (total %d), want 1", len(entries), total)
+	}
+	entry := entries[0]
+	if entry.Error != "provider down" || entry.Attempts != 1 || entry.WorkerID != "worker-1" {
+		t.Fatalf("unexpected dead letter: %+v", entry)
+	}
+
+	requeued, err := q.RequeueDeadLetter(ctx, entry.ID)
+	if err != nil {
+		t.Fatalf("RequeueDeadLetter: %v", err)
+	}
+	if requeued.NoteID != task.NoteID || requeued.Attempts != 0 {
+		t.Fatalf("got note %s with %d attempts, want %s with 0", requeued.NoteID, requeued.Attempts, task.NoteID)
+	}
+	if _, err := q.RequeueDeadLetter(ctx, entry.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("second RequeueDeadLetter = %v, want ErrDeadLetterNotFound", err)
+	}
+	if got := dequeue(t, q, "worker-1"); got.NoteID != task.NoteID {
+		t.Fatalf("got note %s, want %s", got.NoteID, task.NoteID)
+	}
+}
+
+func TestMemoryQueueRequeueDeadLetterKeepsLiveTask(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(0, time.Minute)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.DeadLetterTask(ctx, "worker-1", dequeue(t, q, "worker-1"), errors.New("boom")); err != nil {
+		t.Fatalf("DeadLetterTask: %v", err)
+	}
+
+	// The note was queued again before the dead letter was requeued
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	entries, _, err := q.ListDeadLetters(ctx, 0, 10)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ListDeadLetters = %d entries, %v; want 1", len(entries), err)
+	}
+	if _, err := q.RequeueDeadLetter(ctx, entries[0].ID); !errors.Is(err, ErrTaskQueued) {
+		t.Fatalf("RequeueDeadLetter = %v, want ErrTaskQueued", err)
+	}
+
+	// The dead letter is kept and only one task is queued
+	if _, err := q.GetDeadLetter(ctx, entries[0].ID); err != nil {
+		t.Fatalf("GetDeadLetter: %v", err)
+	}
+	dequeue(t, q, "worker-1")
+	assertEmpty(t, q)
+}
+
+func TestMemoryQueueFull(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(1, time.Minute)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, newTestTask()); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if _, err := q.EnqueueTask(ctx, task); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("EnqueueTask = %v, want ErrQueueFull", err)
+	}
+
+	// A rejected task doesn't keep its note deduplicated
+	dequeue(t, q, "worker-1")
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask after making room: %v", err)
+	}
+}
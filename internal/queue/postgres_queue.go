@@ -0,0 +1,355 @@
+package queue
+
+import (
+	"ai-language-notes/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// postgresPollInterval is how long DequeueTask waits before polling an empty queue again
+const postgresPollInterval = 500 * time.Millisecond
+
+// PostgresQueue implements Queue on the queue_tasks table. Consumers claim
+// tasks with SELECT ... FOR UPDATE SKIP LOCKED, so tasks are as durable as the notes.
+// Each task records its position in its user's backlog (user_seq), and claims
+// order by it so every user's next task comes before anyone's second.
+type PostgresQueue struct {
+	db                *gorm.DB
+	visibilityTimeout time.Duration
+	claims            atomic.Uint64
+}
+
+// NewPostgresQueue creates a new Postgres-backed queue
+func NewPostgresQueue(db *gorm.DB, visibilityTimeout time.Duration) *PostgresQueue {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = DefaultVisibilityTimeout
+	}
+
+	return &PostgresQueue{
+		db:                db,
+		visibilityTimeout: visibilityTimeout,
+	}
+}
+
+// EnqueueTask adds a task to the processing queue unless the note already
+// has a live (ready or in-flight) task. The partial unique index on live
+// tasks turns racing enqueues into no-ops.
+func (q *PostgresQueue) EnqueueTask(ctx context.Context, task *LLMProcessingTask) (bool, error) {
+	taskBytes, err := json.Marshal(task)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	lane := string(task.lane())
+	result := q.db.WithContext(ctx).Exec(`
+		INSERT INTO queue_tasks (note_id, user_id, lane, user_seq, payload, state, available_at, attempts)
+		SELECT ?, ?, ?, (
+			SELECT COALESCE(MAX(user_seq), 0) + 1 FROM queue_tasks
+			WHERE user_id = ? AND lane = ? AND state <> ?
+		), ?, ?, now(), ?
+		ON CONFLICT (note_id) WHERE state <> 'dead' DO NOTHING`,
+		task.NoteID, task.UserID, lane,
+		task.UserID, lane, models.QueueTaskDead,
+		string(taskBytes), models.QueueTaskReady, task.Attempts,
+	)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to enqueue task: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// DequeueTask polls until a task is available or ctx is done, and leases it
+// to the consumer
+func (q *PostgresQueue) DequeueTask(ctx context.Context, consumerID string) (*LLMProcessingTask, error) {
+	for {
+		row, err := q.claim(ctx, consumerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dequeue task: %w", err)
+		}
+
+		if row != nil {
+			var task LLMProcessingTask
+			if err := json.Unmarshal([]byte(row.Payload), &task); err != nil {
+				// A payload that can't be decoded will never succeed, park it for inspection
+				err = fmt.Errorf("failed to unmarshal task: %w", err)
+				if dlqErr := q.markDead(ctx, consumerID, row.ID.String(), err); dlqErr != nil {
+					return nil, fmt.Errorf("%w (dead-lettering failed: %v)", err, dlqErr)
+				}
+				return nil, err
+			}
+			task.Attempts = row.Attempts
+			task.receipt = row.ID.String()
+			return &task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to dequeue task: %w", ctx.Err())
+		case <-time.After(postgresPollInterval):
+		}
+	}
+}
+
+// claim leases the next available task to the consumer following the lane
+// schedule, returning nil if the queue is empty
+func (q *PostgresQueue) claim(ctx context.Context, consumerID string) (*models.QueueTask, error) {
+	order := laneOrder(q.claims.Add(1))
+
+	var rows []models.QueueTask
+	err := q.db.WithContext(ctx).Raw(`
+		UPDATE queue_tasks
+		SET state = ?, locked_by = ?, locked_until = now() + make_interval(secs => ?), updated_at = now()
+		WHERE id = (
+			SELECT id FROM queue_tasks
+			WHERE state = ? AND available_at <= now()
+			ORDER BY CASE lane WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, user_seq, available_at, created_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.QueueTaskInFlight, consumerID, q.visibilityTimeout.Seconds(), models.QueueTaskReady,
+		string(order[0]), string(order[1]),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+// AckTask deletes a finished task
+func (q *PostgresQueue) AckTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	err := q.db.WithContext(ctx).
+		Where("id = ? AND locked_by = ?", task.receipt, consumerID).
+		Delete(&models.QueueTask{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to ack task: %w", err)
+	}
+	return nil
+}
+
+// NackTask makes a leased task available again with its attempt counter
+// incremented, flagged as redelivered
+func (q *PostgresQueue) NackTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	if err := q.release(ctx, consumerID, task, time.Now(), 1, true); err != nil {
+		return fmt.Errorf("failed to requeue task: %w", err)
+	}
+	return nil
+}
+
+// ReleaseTask makes a leased task available again without counting the
+// attempt, flagged as redelivered
+func (q *PostgresQueue) ReleaseTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	if err := q.release(ctx, consumerID, task, time.Now(), 0, true); err != nil {
+		return fmt.Errorf("failed to release task: %w", err)
+	}
+	return nil
+}
+
+// ScheduleRetry makes a leased task available again at dueAt with its
+// attempt counter incremented
+func (q *PostgresQueue) ScheduleRetry(ctx context.Context, consumerID string, task *LLMProcessingTask, dueAt time.Time) error {
+	if err := q.release(ctx, consumerID, task, dueAt, 1, false); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	return nil
+}
+
+// release returns a leased task to the ready state, adding attempts to its counter
+func (q *PostgresQueue) release(ctx context.Context, consumerID string, task *LLMProcessingTask, availableAt time.Time, attempts int, redelivered bool) error {
+	updates := map[string]interface{}{
+		"state":        models.QueueTaskReady,
+		"attempts":     gorm.Expr("attempts + ?", attempts),
+		"available_at": availableAt,
+		"locked_by":    "",
+		"locked_until": nil,
+		"updated_at":   time.Now(),
+	}
+	if redelivered {
+		updates["payload"] = gorm.Expr(`jsonb_set(payload, '{redelivered}', 'true')`)
+	}
+
+	return q.db.WithContext(ctx).Model(&models.QueueTask{}).
+		Where("id = ? AND locked_by = ?", task.receipt, consumerID).
+		Updates(updates).Error
+}
+
+// DeadLetterTask marks a leased task as dead, recording why it failed
+func (q *PostgresQueue) DeadLetterTask(ctx context.Context, consumerID string, task *LLMProcessingTask, cause error) error {
+	if err := q.markDead(ctx, consumerID, task.receipt, cause); err != nil {
+		return fmt.Errorf("failed to dead-letter task: %w", err)
+	}
+	return nil
+}
+
+// markDead moves a task row leased by the consumer into the dead state. A
+// lease that expired and was taken over by another consumer is left alone.
+func (q *PostgresQueue) markDead(ctx context.Context, consumerID, id string, cause error) error {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+
+	return q.db.WithContext(ctx).Model(&models.QueueTask{}).
+		Where("id = ? AND locked_by = ?", id, consumerID).
+		Updates(map[string]interface{}{
+			"state":        models.QueueTaskDead,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   lastError,
+			"locked_until": nil,
+			"updated_at":   time.Now(),
+		}).Error
+}
+
+// Heartbeat extends the leases of all tasks held by the consumer
+func (q *PostgresQueue) Heartbeat(ctx context.Context, consumerID string) error {
+	err := q.db.WithContext(ctx).Exec(`
+		UPDATE queue_tasks
+		SET locked_until = now() + make_interval(secs => ?)
+		WHERE state = ? AND locked_by = ?`,
+		q.visibilityTimeout.Seconds(), models.QueueTaskInFlight, consumerID,
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to renew heartbeat: %w", err)
+	}
+	return nil
+}
+
+// RequeueOrphanedTasks makes tasks whose lease expired available again
+func (q *PostgresQueue) RequeueOrphanedTasks(ctx context.Context) (int, error) {
+	result := q.db.WithContext(ctx).Exec(`
+		UPDATE queue_tasks
+		SET state = ?, locked_by = '', locked_until = NULL, available_at = now(), updated_at = now(),
+			payload = jsonb_set(payload, '{redelivered}', 'true')
+		WHERE state = ? AND locked_until < now()`,
+		models.QueueTaskReady, models.QueueTaskInFlight,
+	)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to requeue orphaned tasks: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// PromoteDueTasks is a no-op: scheduled retries become claimable as soon
+// as their available_at passes
+func (q *PostgresQueue) PromoteDueTasks(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// ListDeadLetters returns a page of dead letters, newest first
+func (q *PostgresQueue) ListDeadLetters(ctx context.Context, offset, limit int) ([]*DeadLetter, int64, error) {
+	db := q.db.WithContext(ctx).Model(&models.QueueTask{}).Where("state = ?", models.QueueTaskDead)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	var rows []models.QueueTask
+	if err := db.Order("updated_at DESC").Offset(offset).Limit(limit).Find(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	entries := make([]*DeadLetter, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, deadLetterFromRow(&rows[i]))
+	}
+	return entries, total, nil
+}
+
+// GetDeadLetter returns a single dead letter by ID
+func (q *PostgresQueue) GetDeadLetter(ctx context.Context, id uuid.UUID) (*DeadLetter, error) {
+	var rows []models.QueueTask
+	err := q.db.WithContext(ctx).
+		Where("id = ? AND state = ?", id, models.QueueTaskDead).
+		Limit(1).Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+	return deadLetterFromRow(&rows[0]), nil
+}
+
+// RequeueDeadLetter makes a dead-lettered task available again with a
+// fresh attempt counter, unless the note already has a live task
+func (q *PostgresQueue) RequeueDeadLetter(ctx context.Context, id uuid.UUID) (*LLMProcessingTask, error) {
+	entry, err := q.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := entry.Task()
+	if err != nil {
+		return nil, err
+	}
+	task.Attempts = 0
+
+	result := q.db.WithContext(ctx).Exec(`
+		UPDATE queue_tasks
+		SET state = ?, attempts = 0, available_at = now(), locked_by = '', last_error = '', updated_at = now()
+		WHERE id = ? AND state = ? AND NOT EXISTS (
+			SELECT 1 FROM queue_tasks live WHERE live.note_id = queue_tasks.note_id AND live.state <> ?
+		)`,
+		models.QueueTaskReady, id, models.QueueTaskDead, models.QueueTaskDead,
+	)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to requeue dead letter: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Either someone else requeued or deleted it, or the note has a live task
+		if _, err := q.GetDeadLetter(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrTaskQueued
+	}
+
+	return task, nil
+}
+
+// DeleteDeadLetter permanently removes a single dead letter
+func (q *PostgresQueue) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	result := q.db.WithContext(ctx).
+		Where("id = ? AND state = ?", id, models.QueueTaskDead).
+		Delete(&models.QueueTask{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters removes all dead letters
+func (q *PostgresQueue) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	result := q.db.WithContext(ctx).
+		Where("state = ?", models.QueueTaskDead).
+		Delete(&models.QueueTask{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// deadLetterFromRow converts a dead queue_tasks row to a DeadLetter
+func deadLetterFromRow(row *models.QueueTask) *DeadLetter {
+	return &DeadLetter{
+		ID:       row.ID,
+		Payload:  row.Payload,
+		Error:    row.LastError,
+		Attempts: row.Attempts,
+		WorkerID: row.LockedBy,
+		FailedAt: row.UpdatedAt,
+	}
+}
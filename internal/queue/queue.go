@@ -2,18 +2,67 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
 )
 
 const (
-	ProcessingQueueKey = "queue:note_processing"
+	// DefaultVisibilityTimeout is used when no visibility timeout is configured
+	DefaultVisibilityTimeout = 5 * time.Minute
+
+	// DefaultDedupTTL is used when no deduplication TTL is configured
+	DefaultDedupTTL = time.Hour
+)
+
+// Queue errors
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrInvalidPayload     = errors.New("dead letter payload is not a valid task")
+	ErrQueueFull          = errors.New("queue is full")
+	ErrTaskQueued         = errors.New("a task for the note is already queued")
+	ErrLeaseLost          = errors.New("the task's lease expired and it was handed to another consumer")
+)
+
+// Priority is the lane a task is scheduled in
+type Priority string
+
+const (
+	// PriorityInteractive is for notes a user is waiting on
+	PriorityInteractive Priority = "interactive"
+	// PriorityBulk is for imports and other batch submissions
+	PriorityBulk Priority = "bulk"
+	// PriorityReprocess is for recovered or re-run notes
+	PriorityReprocess Priority = "reprocess"
 )
 
+// Priorities lists the lanes from highest to lowest priority
+var Priorities = []Priority{PriorityInteractive, PriorityBulk, PriorityReprocess}
+
+// laneSchedule is a weighted round-robin of lanes. Each dequeue prefers the
+// lane at its position in the schedule, so lower lanes keep moving even
+// when higher lanes are never empty.
+var laneSchedule = []Priority{
+	PriorityInteractive, PriorityBulk, PriorityInteractive, PriorityInteractive, PriorityBulk,
+	PriorityInteractive, PriorityReprocess, PriorityInteractive, PriorityBulk, PriorityInteractive,
+}
+
+// laneOrder returns the order in which lanes are checked for the n-th dequeue:
+// the scheduled lane first, then the rest by priority
+func laneOrder(n uint64) []Priority {
+	preferred := laneSchedule[n%uint64(len(laneSchedule))]
+
+	order := make([]Priority, 0, len(Priorities))
+	order = append(order, preferred)
+	for _, priority := range Priorities {
+		if priority != preferred {
+			order = append(order, priority)
+		}
+	}
+	return order
+}
+
 // LLMProcessingTask represents a task for LLM processing
 type LLMProcessingTask struct {
 	NoteID         uuid.UUID `json:"note_id"`
@@ -22,54 +71,77 @@ type LLMProcessingTask struct {
 	NativeLanguage string    `json:"native_language"`
 	TargetLanguage string    `json:"target_language"`
 	CreatedAt      time.Time `json:"created_at"`
-}
+	Attempts       int       `json:"attempts"`
+	Priority       Priority  `json:"priority,omitempty"`
+	// Redelivered is set when the task was handed back or recovered from a
+	// consumer that may already have started on it
+	Redelivered bool `json:"redelivered,omitempty"`
+	// BypassCache makes the worker ask the LLM even if a cached response exists
+	BypassCache bool `json:"bypass_cache,omitempty"`
 
-// QueueService handles the task queue operations
-type QueueService struct {
-	redisClient *redis.Client
+	// receipt is the backend-specific handle of this delivery, used to find
+	// the task again on acknowledgement
+	receipt string
 }
 
-// NewQueueService creates a new queue service
-func NewQueueService(redisClient *redis.Client) *QueueService {
-	return &QueueService{
-		redisClient: redisClient,
+// lane returns the task's priority lane, defaulting to interactive
+func (t *LLMProcessingTask) lane() Priority {
+	for _, priority := range Priorities {
+		if t.Priority == priority {
+			return priority
+		}
 	}
+	return PriorityInteractive
 }
 
-// EnqueueTask adds a task to the processing queue
-func (s *QueueService) EnqueueTask(ctx context.Context, task *LLMProcessingTask) error {
-	taskBytes, err := json.Marshal(task)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
-	}
-
-	// Add to the Redis list that serves as our queue
-	err = s.redisClient.LPush(ctx, ProcessingQueueKey, taskBytes).Err()
-	if err != nil {
-		return fmt.Errorf("failed to enqueue task: %w", err)
-	}
+// Queue is a reliable task queue with at-least-once delivery. A dequeued
+// task is leased to its consumer until it is acknowledged, handed back,
+// rescheduled or dead-lettered. Consumers keep their leases alive with
+// Heartbeat; tasks of consumers whose lease expired are re-queued by
+// RequeueOrphanedTasks. Tasks are served from weighted priority lanes and
+// round-robin across users within a lane, so a single user's backlog can't
+// starve everyone else.
+type Queue interface {
+	// EnqueueTask adds a task to the processing queue and reports whether it
+	// was added. It is a no-op if a task for the same note is already queued
+	// or in flight.
+	EnqueueTask(ctx context.Context, task *LLMProcessingTask) (bool, error)
+	// DequeueTask blocks until a task is available and leases it to the consumer
+	DequeueTask(ctx context.Context, consumerID string) (*LLMProcessingTask, error)
+	// AckTask marks a leased task as done. It may fail with ErrLeaseLost if
+	// the task was already requeued.
+	AckTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error
+	// NackTask hands a leased task back for immediate redelivery
+	NackTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error
+	// ReleaseTask hands a leased task back for immediate redelivery without
+	// counting the attempt, e.g. when the consumer shuts down mid-task
+	ReleaseTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error
+	// ScheduleRetry hands a leased task back for redelivery once dueAt has
+	// passed. It may fail with ErrLeaseLost if the task was already requeued.
+	ScheduleRetry(ctx context.Context, consumerID string, task *LLMProcessingTask, dueAt time.Time) error
+	// DeadLetterTask gives up on a leased task, keeping it for inspection. It
+	// may fail with ErrLeaseLost if the task was already requeued.
+	DeadLetterTask(ctx context.Context, consumerID string, task *LLMProcessingTask, cause error) error
+	// Heartbeat renews the consumer's lease on its tasks
+	Heartbeat(ctx context.Context, consumerID string) error
+	// RequeueOrphanedTasks re-queues tasks whose consumer's lease expired
+	RequeueOrphanedTasks(ctx context.Context) (int, error)
+	// PromoteDueTasks makes scheduled retries whose due time passed available again
+	PromoteDueTasks(ctx context.Context) (int, error)
 
-	return nil
+	DeadLetterStore
 }
 
-// DequeueTask gets the next task from the queue
-func (s *QueueService) DequeueTask(ctx context.Context) (*LLMProcessingTask, error) {
-	// Use BRPOP to block until an item is available or timeout occurs
-	result, err := s.redisClient.BRPop(ctx, 0, ProcessingQueueKey).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to dequeue task: %w", err)
-	}
-
-	// The result is a slice where index 0 is the key name and index 1 is the value
-	if len(result) < 2 {
-		return nil, fmt.Errorf("unexpected result format from Redis")
-	}
-
-	var task LLMProcessingTask
-	err = json.Unmarshal([]byte(result[1]), &task)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal task: %w", err)
-	}
-
-	return &task, nil
+// DeadLetterStore gives access to the tasks a Queue gave up on
+type DeadLetterStore interface {
+	// ListDeadLetters returns a page of dead letters, newest first, and the total count
+	ListDeadLetters(ctx context.Context, offset, limit int) ([]*DeadLetter, int64, error)
+	// GetDeadLetter returns a single dead letter
+	GetDeadLetter(ctx context.Context, id uuid.UUID) (*DeadLetter, error)
+	// RequeueDeadLetter puts a dead-lettered task back on the queue with a fresh attempt counter
+	RequeueDeadLetter(ctx context.Context, id uuid.UUID) (*LLMProcessingTask, error)
+	// DeleteDeadLetter permanently removes a dead letter
+	DeleteDeadLetter(ctx context.Context, id uuid.UUID) error
+	// PurgeDeadLetters removes all dead letters and returns how many were dropped
+	PurgeDeadLetters(ctx context.Context) (int64, error)
 }
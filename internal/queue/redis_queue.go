@@ -0,0 +1,665 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ProcessingQueueKey is the legacy single FIFO list. It is still drained
+	// after the lanes so tasks enqueued before lanes existed aren't stranded.
+	ProcessingQueueKey = "queue:note_processing"
+
+	// LaneKeyPrefix prefixes the per-lane keys. Each lane has a ring of
+	// users with queued tasks (":users"), a set mirroring the ring
+	// (":active") and one list per user (":user:<id>").
+	LaneKeyPrefix = ProcessingQueueKey + ":lane:"
+
+	// SignalKey receives a token on every enqueue to wake blocked consumers
+	SignalKey = ProcessingQueueKey + ":signal"
+
+	// InFlightKeyPrefix prefixes the per-consumer list holding tasks that were
+	// dequeued but not yet acknowledged
+	InFlightKeyPrefix = ProcessingQueueKey + ":inflight:"
+
+	// HeartbeatKeyPrefix prefixes the per-consumer lease key. When it expires
+	// the consumer is considered dead and its in-flight tasks are re-queued.
+	HeartbeatKeyPrefix = ProcessingQueueKey + ":heartbeat:"
+
+	// DelayedKey is a sorted set of tasks waiting for a retry, scored by due time
+	DelayedKey = ProcessingQueueKey + ":delayed"
+
+	// DeadLetterKey is a hash of dead-lettered tasks keyed by dead letter ID
+	DeadLetterKey = ProcessingQueueKey + ":dead"
+
+	// DeadLetterIndexKey lists dead letter IDs, newest first
+	DeadLetterIndexKey = DeadLetterKey + ":index"
+
+	// DedupKeyPrefix prefixes the per-note key that marks a task for the note
+	// as queued or in flight
+	DedupKeyPrefix = ProcessingQueueKey + ":dedup:"
+
+	// signalWait bounds how long a consumer blocks between dequeue attempts
+	signalWait = 2 * time.Second
+)
+
+// pushScript appends a task to its user's list in a lane and puts the user
+// on the lane's ring if they weren't there yet. With a source key it first
+// removes the task from there ("lrem" for lists, "zrem" for sorted sets) and
+// does nothing if it was already gone, so concurrent movers can't duplicate it.
+//
+// KEYS: user list, ring, active set, signal, source
+// ARGV: payload, user ID, source mode, source member
+var pushScript = redis.NewScript(`
+if ARGV[3] == "lrem" then
+	if redis.call("LREM", KEYS[5], 1, ARGV[4]) == 0 then return 0 end
+elseif ARGV[3] == "zrem" then
+	if redis.call("ZREM", KEYS[5], ARGV[4]) == 0 then return 0 end
+end
+redis.call("LPUSH", KEYS[1], ARGV[1])
+if redis.call("SADD", KEYS[3], ARGV[2]) == 1 then
+	redis.call("RPUSH", KEYS[2], ARGV[2])
+end
+redis.call("LPUSH", KEYS[4], "1")
+redis.call("LTRIM", KEYS[4], 0, 999)
+return 1
+`)
+
+// requeueDeadLetterScript moves a dead letter back into its lane. It claims
+// the note's dedup key like EnqueueTask does and leaves the dead letter in
+// place if a task for the note is already queued or in flight. Returns 1 if
+// the task was requeued, 0 if the dead letter is gone and -1 if the note
+// already has a live task.
+//
+// KEYS: dead-letter hash, dead-letter index, dedup key, user list, ring, active set, signal
+// ARGV: dead letter ID, payload, user ID, dedup value, dedup TTL in milliseconds
+var requeueDeadLetterScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then return 0 end
+if not redis.call("SET", KEYS[3], ARGV[4], "NX", "PX", ARGV[5]) then return -1 end
+redis.call("HDEL", KEYS[1], ARGV[1])
+redis.call("LREM", KEYS[2], 1, ARGV[1])
+redis.call("LPUSH", KEYS[4], ARGV[2])
+if redis.call("SADD", KEYS[6], ARGV[3]) == 1 then
+	redis.call("RPUSH", KEYS[5], ARGV[3])
+end
+redis.call("LPUSH", KEYS[7], "1")
+redis.call("LTRIM", KEYS[7], 0, 999)
+return 1
+`)
+
+// ackScript removes a task from an in-flight list and releases the note's
+// dedup key. Returns 0 and leaves the key alone if the task is no longer in
+// flight, since it may be queued again after its lease expired.
+//
+// KEYS: in-flight list, dedup key
+// ARGV: receipt
+var ackScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then return 0 end
+redis.call("DEL", KEYS[2])
+return 1
+`)
+
+// scheduleRetryScript moves a task from an in-flight list into the delayed
+// set and extends the note's dedup key to outlive the wait. Returns 0 and
+// does nothing if the task is no longer in flight, e.g. because the reaper
+// requeued it.
+//
+// KEYS: in-flight list, delayed set, dedup key
+// ARGV: receipt, due time, payload, dedup value, dedup TTL in milliseconds
+var scheduleRetryScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then return 0 end
+redis.call("ZADD", KEYS[2], ARGV[2], ARGV[3])
+redis.call("SET", KEYS[3], ARGV[4], "PX", ARGV[5])
+return 1
+`)
+
+// deadLetterScript moves a payload from an in-flight list into the
+// dead-letter store and, if asked to, releases the note's dedup key.
+// Returns 0 and does nothing if the payload is no longer in flight.
+//
+// KEYS: in-flight list, dead-letter hash, dead-letter index, dedup key
+// ARGV: payload, dead letter ID, entry, "1" to release the dedup key
+var deadLetterScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then return 0 end
+redis.call("HSET", KEYS[2], ARGV[2], ARGV[3])
+redis.call("LPUSH", KEYS[3], ARGV[2])
+if ARGV[4] == "1" then
+	redis.call("DEL", KEYS[4])
+end
+return 1
+`)
+
+// popScript takes the next task for the first non-empty lane in the given
+// order, rotating through the lane's users, and parks it in the consumer's
+// in-flight list. User list keys are derived from the ring, so this script
+// assumes a single Redis node.
+//
+// KEYS: in-flight list, legacy queue
+// ARGV: lane key prefix, lanes...
+var popScript = redis.NewScript(`
+for i = 2, #ARGV do
+	local lane = ARGV[1] .. ARGV[i]
+	local ring = lane .. ":users"
+	local active = lane .. ":active"
+	local users = redis.call("LLEN", ring)
+	for j = 1, users do
+		local user = redis.call("LPOP", ring)
+		if not user then break end
+		local list = lane .. ":user:" .. user
+		local task = redis.call("RPOP", list)
+		if redis.call("LLEN", list) > 0 then
+			redis.call("RPUSH", ring, user)
+		else
+			redis.call("SREM", active, user)
+		end
+		if task then
+			redis.call("LPUSH", KEYS[1], task)
+			return task
+		end
+	end
+end
+local legacy = redis.call("RPOP", KEYS[2])
+if legacy then
+	redis.call("LPUSH", KEYS[1], legacy)
+	return legacy
+end
+return false
+`)
+
+// RedisQueue implements Queue on top of Redis lists and Lua scripts
+type RedisQueue struct {
+	redisClient       *redis.Client
+	visibilityTimeout time.Duration
+	dedupTTL          time.Duration
+	dequeues          atomic.Uint64
+}
+
+// NewRedisQueue creates a new Redis-backed queue
+func NewRedisQueue(redisClient *redis.Client, visibilityTimeout, dedupTTL time.Duration) *RedisQueue {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = DefaultVisibilityTimeout
+	}
+	if dedupTTL <= 0 {
+		dedupTTL = DefaultDedupTTL
+	}
+
+	return &RedisQueue{
+		redisClient:       redisClient,
+		visibilityTimeout: visibilityTimeout,
+		dedupTTL:          dedupTTL,
+	}
+}
+
+// EnqueueTask adds a task to its user's list in the task's lane, unless the
+// note's dedup key shows a task for it is already queued or in flight. The
+// key expires after the dedup TTL so a lost task can't block the note forever.
+func (s *RedisQueue) EnqueueTask(ctx context.Context, task *LLMProcessingTask) (bool, error) {
+	taskBytes, err := json.Marshal(task)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	fresh, err := s.redisClient.SetNX(ctx, dedupKey(task.NoteID), time.Now().Unix(), s.dedupTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+	if !fresh {
+		return false, nil
+	}
+
+	if _, err := s.push(ctx, task, taskBytes, "", "", ""); err != nil {
+		s.redisClient.Del(ctx, dedupKey(task.NoteID))
+		return false, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	return true, nil
+}
+
+// push runs pushScript for a task, optionally moving it out of source
+func (s *RedisQueue) push(ctx context.Context, task *LLMProcessingTask, payload []byte, source, mode, member string) (bool, error) {
+	lane := LaneKeyPrefix + string(task.lane())
+	userID := task.UserID.String()
+
+	keys := []string{lane + ":user:" + userID, lane + ":users", lane + ":active", SignalKey, source}
+	if source == "" {
+		keys[4] = SignalKey // unused, but every key must be a valid name
+	}
+
+	moved, err := pushScript.Run(ctx, s.redisClient, keys, payload, userID, mode, member).Int()
+	if err != nil {
+		return false, err
+	}
+	return moved == 1, nil
+}
+
+// DequeueTask gets the next task and moves it to the consumer's in-flight
+// list. The task stays there until it is settled, or until the consumer's
+// heartbeat expires and the reaper re-queues it.
+func (s *RedisQueue) DequeueTask(ctx context.Context, consumerID string) (*LLMProcessingTask, error) {
+	// Make sure the lease exists before anything lands in the in-flight list
+	if err := s.Heartbeat(ctx, consumerID); err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("failed to dequeue task: %w", err)
+		}
+
+		args := []interface{}{LaneKeyPrefix}
+		for _, lane := range laneOrder(s.dequeues.Add(1)) {
+			args = append(args, string(lane))
+		}
+
+		result, err := popScript.Run(ctx, s.redisClient, []string{inFlightKey(consumerID), ProcessingQueueKey}, args...).Text()
+		if err == nil {
+			return s.decode(ctx, consumerID, result)
+		}
+		if !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to dequeue task: %w", err)
+		}
+
+		// Nothing queued, wait for an enqueue signal
+		err = s.redisClient.BRPop(ctx, signalWait, SignalKey).Err()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to dequeue task: %w", err)
+		}
+	}
+}
+
+// decode parses a dequeued payload, dead-lettering it if it is malformed
+func (s *RedisQueue) decode(ctx context.Context, consumerID, payload string) (*LLMProcessingTask, error) {
+	var task LLMProcessingTask
+	err := json.Unmarshal([]byte(payload), &task)
+	if err != nil {
+		// A payload that can't be decoded will never succeed, park it for inspection
+		err = fmt.Errorf("failed to unmarshal task: %w", err)
+		if dlqErr := s.deadLetter(ctx, consumerID, payload, uuid.Nil, 0, err); dlqErr != nil {
+			return nil, fmt.Errorf("%w (dead-lettering failed: %v)", err, dlqErr)
+		}
+		return nil, err
+	}
+	task.receipt = payload
+
+	return &task, nil
+}
+
+// AckTask removes a finished task from the consumer's in-flight list and
+// releases the note's dedup key. Fails with ErrLeaseLost, keeping the key,
+// if the task is no longer in flight.
+func (s *RedisQueue) AckTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	removed, err := ackScript.Run(ctx, s.redisClient, []string{inFlightKey(consumerID), dedupKey(task.NoteID)}, task.receipt).Int()
+	if err != nil {
+		return fmt.Errorf("failed to ack task: %w", err)
+	}
+	if removed == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// NackTask moves a task from the consumer's in-flight list back into its
+// lane so another worker can pick it up. The task's attempt counter is
+// incremented.
+func (s *RedisQueue) NackTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	return s.requeue(ctx, consumerID, task, 1)
+}
+
+// ReleaseTask moves a task from the consumer's in-flight list back into its
+// lane without counting the attempt
+func (s *RedisQueue) ReleaseTask(ctx context.Context, consumerID string, task *LLMProcessingTask) error {
+	return s.requeue(ctx, consumerID, task, 0)
+}
+
+// requeue moves an in-flight task back into its lane, flagged as redelivered
+func (s *RedisQueue) requeue(ctx context.Context, consumerID string, task *LLMProcessingTask, attempts int) error {
+	retry := *task
+	retry.Attempts += attempts
+	retry.Redelivered = true
+	taskBytes, err := json.Marshal(&retry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	if _, err := s.push(ctx, &retry, taskBytes, inFlightKey(consumerID), "lrem", task.receipt); err != nil {
+		return fmt.Errorf("failed to requeue task: %w", err)
+	}
+	return nil
+}
+
+// ScheduleRetry moves a task from the consumer's in-flight list into the
+// delayed set with its attempt counter incremented. It is moved back into
+// its lane by PromoteDueTasks once dueAt has passed. The note's dedup key is
+// extended to outlive the wait. Fails with ErrLeaseLost if the task is no
+// longer in flight.
+func (s *RedisQueue) ScheduleRetry(ctx context.Context, consumerID string, task *LLMProcessingTask, dueAt time.Time) error {
+	retry := *task
+	retry.Attempts++
+	taskBytes, err := json.Marshal(&retry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	keys := []string{inFlightKey(consumerID), DelayedKey, dedupKey(task.NoteID)}
+	ttl := time.Until(dueAt) + s.dedupTTL
+	moved, err := scheduleRetryScript.Run(ctx, s.redisClient, keys,
+		task.receipt, dueAt.Unix(), taskBytes, time.Now().Unix(), ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+	if moved == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// PromoteDueTasks moves delayed tasks whose due time has passed back into
+// their lanes, renewing their notes' dedup keys, and returns how many were moved
+func (s *RedisQueue) PromoteDueTasks(ctx context.Context) (int, error) {
+	due, err := s.redisClient.ZRangeByScore(ctx, DelayedKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read delayed tasks: %w", err)
+	}
+
+	promoted := 0
+	for _, member := range due {
+		var task LLMProcessingTask
+		if err := json.Unmarshal([]byte(member), &task); err != nil {
+			// Leave it to the legacy queue so it gets dead-lettered on dequeue
+			s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.ZRem(ctx, DelayedKey, member)
+				pipe.RPush(ctx, ProcessingQueueKey, member)
+				return nil
+			})
+			continue
+		}
+
+		moved, err := s.push(ctx, &task, []byte(member), DelayedKey, "zrem", member)
+		if err != nil {
+			return promoted, fmt.Errorf("failed to promote delayed task: %w", err)
+		}
+		if moved {
+			s.redisClient.Set(ctx, dedupKey(task.NoteID), time.Now().Unix(), s.dedupTTL)
+			promoted++
+		}
+	}
+
+	return promoted, nil
+}
+
+// Heartbeat renews the consumer's lease for another visibility timeout and
+// the dedup keys of its in-flight tasks, so long-running tasks stay deduplicated
+func (s *RedisQueue) Heartbeat(ctx context.Context, consumerID string) error {
+	payloads, err := s.redisClient.LRange(ctx, inFlightKey(consumerID), 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to renew heartbeat: %w", err)
+	}
+
+	_, err = s.redisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, heartbeatKey(consumerID), time.Now().Unix(), s.visibilityTimeout)
+		for _, payload := range payloads {
+			var task LLMProcessingTask
+			if json.Unmarshal([]byte(payload), &task) == nil {
+				pipe.Expire(ctx, dedupKey(task.NoteID), s.dedupTTL)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew heartbeat: %w", err)
+	}
+	return nil
+}
+
+// RequeueOrphanedTasks moves the in-flight tasks of every consumer whose
+// heartbeat has expired back into their lanes. It returns the number of
+// tasks that were re-queued.
+func (s *RedisQueue) RequeueOrphanedTasks(ctx context.Context) (int, error) {
+	requeued := 0
+
+	iter := s.redisClient.Scan(ctx, 0, InFlightKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		consumerID := strings.TrimPrefix(key, InFlightKeyPrefix)
+
+		alive, err := s.redisClient.Exists(ctx, heartbeatKey(consumerID)).Result()
+		if err != nil {
+			return requeued, fmt.Errorf("failed to check heartbeat: %w", err)
+		}
+		if alive > 0 {
+			continue
+		}
+
+		payloads, err := s.redisClient.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return requeued, fmt.Errorf("failed to read orphaned tasks: %w", err)
+		}
+
+		for _, payload := range payloads {
+			var task LLMProcessingTask
+			if err := json.Unmarshal([]byte(payload), &task); err != nil {
+				err := s.deadLetter(ctx, consumerID, payload, uuid.Nil, 0, fmt.Errorf("failed to unmarshal task: %w", err))
+				if err != nil && !errors.Is(err, ErrLeaseLost) {
+					return requeued, err
+				}
+				continue
+			}
+
+			task.Redelivered = true
+			taskBytes, err := json.Marshal(&task)
+			if err != nil {
+				return requeued, fmt.Errorf("failed to marshal task: %w", err)
+			}
+
+			moved, err := s.push(ctx, &task, taskBytes, key, "lrem", payload)
+			if err != nil {
+				return requeued, fmt.Errorf("failed to requeue orphaned task: %w", err)
+			}
+			if moved {
+				requeued++
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return requeued, fmt.Errorf("failed to scan in-flight lists: %w", err)
+	}
+
+	return requeued, nil
+}
+
+// DeadLetterTask moves a task from the consumer's in-flight list into the
+// dead-letter store, recording why it failed, and releases the note's dedup
+// key. Fails with ErrLeaseLost if the task is no longer in flight.
+func (s *RedisQueue) DeadLetterTask(ctx context.Context, consumerID string, task *LLMProcessingTask, cause error) error {
+	return s.deadLetter(ctx, consumerID, task.receipt, task.NoteID, task.Attempts+1, cause)
+}
+
+// deadLetter moves a raw payload from the consumer's in-flight list into the
+// dead-letter store, releasing the dedup key of noteID unless it is nil.
+// Fails with ErrLeaseLost if the payload is no longer in flight.
+func (s *RedisQueue) deadLetter(ctx context.Context, consumerID, payload string, noteID uuid.UUID, attempts int, cause error) error {
+	entry := DeadLetter{
+		ID:       uuid.New(),
+		Payload:  payload,
+		Attempts: attempts,
+		WorkerID: consumerID,
+		FailedAt: time.Now(),
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	keys := []string{inFlightKey(consumerID), DeadLetterKey, DeadLetterIndexKey, DeadLetterKey}
+	release := "0"
+	if noteID != uuid.Nil {
+		keys[3] = dedupKey(noteID)
+		release = "1"
+	}
+
+	moved, err := deadLetterScript.Run(ctx, s.redisClient, keys, payload, entry.ID.String(), entryBytes, release).Int()
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter task: %w", err)
+	}
+	if moved == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ListDeadLetters returns a page of dead letters, newest first, together
+// with the total number of dead letters
+func (s *RedisQueue) ListDeadLetters(ctx context.Context, offset, limit int) ([]*DeadLetter, int64, error) {
+	total, err := s.redisClient.LLen(ctx, DeadLetterIndexKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	ids, err := s.redisClient.LRange(ctx, DeadLetterIndexKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*DeadLetter{}, total, nil
+	}
+
+	values, err := s.redisClient.HMGet(ctx, DeadLetterKey, ids...).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load dead letters: %w", err)
+	}
+
+	entries := make([]*DeadLetter, 0, len(values))
+	for _, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			// Index and hash drifted apart, skip the dangling ID
+			continue
+		}
+		var entry DeadLetter
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			return nil, 0, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, total, nil
+}
+
+// GetDeadLetter returns a single dead letter by ID
+func (s *RedisQueue) GetDeadLetter(ctx context.Context, id uuid.UUID) (*DeadLetter, error) {
+	raw, err := s.redisClient.HGet(ctx, DeadLetterKey, id.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	var entry DeadLetter
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+	return &entry, nil
+}
+
+// RequeueDeadLetter puts a dead-lettered task back into its lane with a
+// fresh attempt counter and removes it from the dead-letter store, unless a
+// task for the note is already queued or in flight
+func (s *RedisQueue) RequeueDeadLetter(ctx context.Context, id uuid.UUID) (*LLMProcessingTask, error) {
+	entry, err := s.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := entry.Task()
+	if err != nil {
+		return nil, err
+	}
+	task.Attempts = 0
+
+	taskBytes, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	lane := LaneKeyPrefix + string(task.lane())
+	userID := task.UserID.String()
+	keys := []string{
+		DeadLetterKey, DeadLetterIndexKey, dedupKey(task.NoteID),
+		lane + ":user:" + userID, lane + ":users", lane + ":active", SignalKey,
+	}
+
+	result, err := requeueDeadLetterScript.Run(ctx, s.redisClient, keys,
+		id.String(), taskBytes, userID, time.Now().Unix(), s.dedupTTL.Milliseconds()).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue dead letter: %w", err)
+	}
+	switch result {
+	case 0:
+		return nil, ErrDeadLetterNotFound
+	case -1:
+		return nil, ErrTaskQueued
+	}
+
+	return task, nil
+}
+
+// DeleteDeadLetter permanently removes a single dead letter
+func (s *RedisQueue) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	var removed *redis.IntCmd
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.HDel(ctx, DeadLetterKey, id.String())
+		pipe.LRem(ctx, DeadLetterIndexKey, 1, id.String())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if removed.Val() == 0 {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// PurgeDeadLetters removes all dead letters and returns how many were dropped
+func (s *RedisQueue) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	var count *redis.IntCmd
+	_, err := s.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HLen(ctx, DeadLetterKey)
+		pipe.Del(ctx, DeadLetterKey, DeadLetterIndexKey)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead letters: %w", err)
+	}
+	return count.Val(), nil
+}
+
+func inFlightKey(consumerID string) string {
+	return InFlightKeyPrefix + consumerID
+}
+
+func heartbeatKey(consumerID string) string {
+	return HeartbeatKeyPrefix + consumerID
+}
+
+func dedupKey(noteID uuid.UUID) string {
+	return DedupKeyPrefix + noteID.String()
+}
@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testVisibilityTimeout = 30 * time.Second
+
+func newTestRedisQueue(t *testing.T) (*RedisQueue, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisQueue(client, testVisibilityTimeout, time.Hour), server
+}
+
+// reap expires the consumer's heartbeat and lets the reaper requeue its tasks
+func reap(t *testing.T, q *RedisQueue, server *miniredis.Miniredis) {
+	t.Helper()
+	server.FastForward(testVisibilityTimeout + time.Second)
+	if n, err := q.RequeueOrphanedTasks(context.Background()); err != nil || n != 1 {
+		t.Fatalf("RequeueOrphanedTasks = %d, %v; want 1, nil", n, err)
+	}
+}
+
+// queuedTasks counts the tasks waiting in a lane or the delayed set
+func queuedTasks(t *testing.T, q *RedisQueue, task *LLMProcessingTask) (int64, int64) {
+	t.Helper()
+	ctx := context.Background()
+	lane, err := q.redisClient.LLen(ctx, LaneKeyPrefix+string(task.lane())+":user:"+task.UserID.String()).Result()
+	if err != nil {
+		t.Fatalf("LLen: %v", err)
+	}
+	delayed, err := q.redisClient.ZCard(ctx, DelayedKey).Result()
+	if err != nil {
+		t.Fatalf("ZCard: %v", err)
+	}
+	return lane, delayed
+}
+
+func TestRedisQueueAckReleasesNote(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.AckTask(ctx, "worker-1", dequeue(t, q, "worker-1")); err != nil {
+		t.Fatalf("AckTask: %v", err)
+	}
+	if server.Exists(dedupKey(task.NoteID)) {
+		t.Fatal("expected the dedup key to be released")
+	}
+}
+
+func TestRedisQueueAckAfterReapKeepsNoteDeduplicated(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	leased := dequeue(t, q, "worker-1")
+	reap(t, q, server)
+
+	if err := q.AckTask(ctx, "worker-1", leased); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("AckTask = %v, want ErrLeaseLost", err)
+	}
+	// The requeued task still holds the note
+	if added, err := q.EnqueueTask(ctx, task); err != nil || added {
+		t.Fatalf("EnqueueTask = %v, %v; want false, nil", added, err)
+	}
+	if lane, _ := queuedTasks(t, q, task); lane != 1 {
+		t.Fatalf("got %d queued tasks, want 1", lane)
+	}
+}
+
+func TestRedisQueueScheduleRetry(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	dueAt := time.Now().Add(time.Hour)
+	if err := q.ScheduleRetry(ctx, "worker-1", dequeue(t, q, "worker-1"), dueAt); err != nil {
+		t.Fatalf("ScheduleRetry: %v", err)
+	}
+
+	if lane, delayed := queuedTasks(t, q, task); lane != 0 || delayed != 1 {
+		t.Fatalf("got %d queued and %d delayed tasks, want 0 and 1", lane, delayed)
+	}
+	if ttl := server.TTL(dedupKey(task.NoteID)); ttl <= time.Hour {
+		t.Fatalf("dedup key TTL = %s, want it to outlive the wait", ttl)
+	}
+}
+
+func TestRedisQueueScheduleRetryAfterReap(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	leased := dequeue(t, q, "worker-1")
+	reap(t, q, server)
+
+	err := q.ScheduleRetry(ctx, "worker-1", leased, time.Now().Add(time.Hour))
+	if !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("ScheduleRetry = %v, want ErrLeaseLost", err)
+	}
+	if lane, delayed := queuedTasks(t, q, task); lane != 1 || delayed != 0 {
+		t.Fatalf("got %d queued and %d delayed tasks, want only the requeued one", lane, delayed)
+	}
+}
+
+func TestRedisQueueDeadLetterTask(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	if err := q.DeadLetterTask(ctx, "worker-1", dequeue(t, q, "worker-1"), errors.New("provider down")); err != nil {
+		t.Fatalf("DeadLetterTask: %v", err)
+	}
+
+	entries, total, err := q.ListDeadLetters(ctx, 0, 10)
+	if err != nil || total != 1 || len(entries) != 1 {
+		t.Fatalf("ListDeadLetters = %d entries (total %d), %v; want 1", len(entries), total, err)
+	}
+	if entries[0].Error != "provider down" || entries[0].WorkerID != "worker-1" {
+		t.Fatalf("unexpected dead letter: %+v", entries[0])
+	}
+	if server.Exists(dedupKey(task.NoteID)) {
+		t.Fatal("expected the dedup key to be released")
+	}
+}
+
+func TestRedisQueueDeadLetterTaskAfterReap(t *testing.T) {
+	ctx := context.Background()
+	q, server := newTestRedisQueue(t)
+	task := newTestTask()
+
+	if _, err := q.EnqueueTask(ctx, task); err != nil {
+		t.Fatalf("EnqueueTask: %v", err)
+	}
+	leased := dequeue(t, q, "worker-1")
+	reap(t, q, server)
+
+	if err := q.DeadLetterTask(ctx, "worker-1", leased, errors.New("boom")); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("DeadLetterTask = %v, want ErrLeaseLost", err)
+	}
+	if _, total, err := q.ListDeadLetters(ctx, 0, 10); err != nil || total != 0 {
+		t.Fatalf("ListDeadLetters total = %d, %v; want 0", total, err)
+	}
+	if !server.Exists(dedupKey(task.NoteID)) {
+		t.Fatal("expected the requeued task to keep the note deduplicated")
+	}
+	if lane, _ := queuedTasks(t, q, task); lane != 1 {
+		t.Fatalf("got %d queued tasks, want the requeued one", lane)
+	}
+}
@@ -0,0 +1,117 @@
+package quota
+
+import (
+	"ai-language-notes/internal/models"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quota names, reported by ExceededError
+const (
+	NotesPerDay    = "notes_per_day"
+	TokensPerMonth = "tokens_per_month"
+)
+
+// Unlimited is reported as the remaining amount of quotas without a limit
+const Unlimited int64 = -1
+
+// Limits are the quotas of a plan. Zero means unlimited.
+type Limits struct {
+	NotesPerDay    int64
+	TokensPerMonth int64
+}
+
+// DefaultPlans are the limits of plans not configured with PLAN_QUOTAS
+var DefaultPlans = map[string]Limits{
+	models.PlanFree:      {NotesPerDay: 50, TokensPerMonth: 500_000},
+	models.PlanPro:       {NotesPerDay: 1_000, TokensPerMonth: 20_000_000},
+	models.PlanUnlimited: {},
+}
+
+// ParsePlans parses comma-separated "<plan>=<notes per day>/<tokens per
+// month>" entries, e.g. "free=20/100000", on top of DefaultPlans
+func ParsePlans(raw string) (map[string]Limits, error) {
+	plans := make(map[string]Limits, len(DefaultPlans))
+	for plan, limits := range DefaultPlans {
+		plans[plan] = limits
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		plan, limits, ok := strings.Cut(entry, "=")
+		plan = strings.TrimSpace(plan)
+		notes, tokens, hasTokens := strings.Cut(limits, "/")
+		notesPerDay, notesErr := strconv.ParseInt(strings.TrimSpace(notes), 10, 64)
+		tokensPerMonth, tokensErr := strconv.ParseInt(strings.TrimSpace(tokens), 10, 64)
+		if !ok || !hasTokens || notesErr != nil || tokensErr != nil || notesPerDay < 0 || tokensPerMonth < 0 {
+			return nil, fmt.Errorf("invalid plan quota %q, expected <plan>=<notes per day>/<tokens per month>", entry)
+		}
+		if !slices.Contains(models.Plans, plan) {
+			return nil, fmt.Errorf("unknown plan %q, expected one of %s", plan, strings.Join(models.Plans, ", "))
+		}
+		plans[plan] = Limits{NotesPerDay: notesPerDay, TokensPerMonth: tokensPerMonth}
+	}
+	return plans, nil
+}
+
+// Remaining is what is left of a user's quotas, Unlimited for quotas
+// without a limit
+type Remaining struct {
+	Notes  int64
+	Tokens int64
+}
+
+// ExceededError is returned when a user has used up a quota
+type ExceededError struct {
+	Quota     string // NotesPerDay or TokensPerMonth
+	Limit     int64
+	Remaining Remaining
+	// RetryAfter is how long until the quota resets
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	switch e.Quota {
+	case NotesPerDay:
+		return fmt.Sprintf("daily quota of %d notes exceeded", e.Limit)
+	case TokensPerMonth:
+		return fmt.Sprintf("monthly quota of %d tokens used up", e.Limit)
+	default:
+		return fmt.Sprintf("quota %s of %d exceeded", e.Quota, e.Limit)
+	}
+}
+
+// Limiter enforces the quotas of users' plans
+type Limiter interface {
+	// ReserveNotes counts notes against the user's daily quota. It fails with
+	// an *ExceededError, counting nothing, if they don't all fit or the user's
+	// monthly tokens are used up.
+	ReserveNotes(ctx context.Context, userID uuid.UUID, plan string, count int64) (Remaining, error)
+	// ReleaseNotes gives back notes that were reserved but not created
+	ReleaseNotes(ctx context.Context, userID uuid.UUID, count int64) error
+	// RecordTokens counts tokens used to process the user's notes against
+	// their monthly quota
+	RecordTokens(ctx context.Context, userID uuid.UUID, tokens int64) error
+}
+
+// untilNextDay returns how long until the next UTC day starts
+func untilNextDay(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
+
+// untilNextMonth returns how long until the next UTC month starts
+func untilNextMonth(now time.Time) time.Duration {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Sub(now)
+}
@@ -0,0 +1,154 @@
+package quota
+
+import (
+	"ai-language-notes/internal/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// NotesKeyPrefix prefixes the per-user and day note counters
+	// (":<user>:<YYYY-MM-DD>")
+	NotesKeyPrefix = "quota:notes:"
+
+	// TokensKeyPrefix prefixes the per-user and month token counters
+	// (":<user>:<YYYY-MM>")
+	TokensKeyPrefix = "quota:tokens:"
+
+	// counterGrace keeps counters a little past their period so clock skew
+	// between processes can't reset them early
+	counterGrace = time.Hour
+)
+
+// reserveScript adds notes to the day's counter unless that exceeds the
+// daily limit or the month's tokens are used up. Limits of 0 are unlimited.
+// Returns the outcome (0 reserved, 1 notes exceeded, 2 tokens exceeded) and
+// the notes and tokens counted afterwards.
+//
+// KEYS: note counter, token counter
+// ARGV: count, notes limit, tokens limit, note counter expiry (unix seconds)
+var reserveScript = redis.NewScript(`
+local notes = tonumber(redis.call("GET", KEYS[1]) or "0")
+local tokens = tonumber(redis.call("GET", KEYS[2]) or "0")
+local count = tonumber(ARGV[1])
+if tonumber(ARGV[3]) > 0 and tokens >= tonumber(ARGV[3]) then
+	return {2, notes, tokens}
+end
+if tonumber(ARGV[2]) > 0 and notes + count > tonumber(ARGV[2]) then
+	return {1, notes, tokens}
+end
+notes = redis.call("INCRBY", KEYS[1], count)
+redis.call("EXPIREAT", KEYS[1], ARGV[4])
+return {0, notes, tokens}
+`)
+
+// RedisLimiter implements Limiter with counters in Redis shared by all
+// API and worker processes
+type RedisLimiter struct {
+	redisClient *redis.Client
+	plans       map[string]Limits
+}
+
+// NewRedisLimiter creates a Limiter enforcing the given plans' limits. Users
+// on a plan missing from plans get the free plan's limits.
+func NewRedisLimiter(redisClient *redis.Client, plans map[string]Limits) *RedisLimiter {
+	return &RedisLimiter{
+		redisClient: redisClient,
+		plans:       plans,
+	}
+}
+
+// ReserveNotes implements Limiter.ReserveNotes
+func (l *RedisLimiter) ReserveNotes(ctx context.Context, userID uuid.UUID, plan string, count int64) (Remaining, error) {
+	limits := l.limits(plan)
+	now := time.Now()
+
+	keys := []string{notesKey(userID, now), tokensKey(userID, now)}
+	expireAt := now.Add(untilNextDay(now) + counterGrace).Unix()
+	result, err := reserveScript.Run(ctx, l.redisClient, keys, count, limits.NotesPerDay, limits.TokensPerMonth, expireAt).Int64Slice()
+	if err != nil {
+		return Remaining{}, fmt.Errorf("failed to reserve notes: %w", err)
+	}
+	if len(result) != 3 {
+		return Remaining{}, fmt.Errorf("failed to reserve notes: unexpected result %v", result)
+	}
+
+	remaining := Remaining{
+		Notes:  remainingOf(limits.NotesPerDay, result[1]),
+		Tokens: remainingOf(limits.TokensPerMonth, result[2]),
+	}
+	switch result[0] {
+	case 1:
+		return remaining, &ExceededError{
+			Quota:      NotesPerDay,
+			Limit:      limits.NotesPerDay,
+			Remaining:  remaining,
+			RetryAfter: untilNextDay(now),
+		}
+	case 2:
+		return remaining, &ExceededError{
+			Quota:      TokensPerMonth,
+			Limit:      limits.TokensPerMonth,
+			Remaining:  remaining,
+			RetryAfter: untilNextMonth(now),
+		}
+	}
+	return remaining, nil
+}
+
+// ReleaseNotes implements Limiter.ReleaseNotes
+func (l *RedisLimiter) ReleaseNotes(ctx context.Context, userID uuid.UUID, count int64) error {
+	if err := l.redisClient.DecrBy(ctx, notesKey(userID, time.Now()), count).Err(); err != nil {
+		return fmt.Errorf("failed to release notes: %w", err)
+	}
+	return nil
+}
+
+// RecordTokens implements Limiter.RecordTokens
+func (l *RedisLimiter) RecordTokens(ctx context.Context, userID uuid.UUID, tokens int64) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	key := tokensKey(userID, now)
+	_, err := l.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.IncrBy(ctx, key, tokens)
+		pipe.ExpireAt(ctx, key, now.Add(untilNextMonth(now)+counterGrace))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record tokens: %w", err)
+	}
+	return nil
+}
+
+// limits returns the limits of a plan
+func (l *RedisLimiter) limits(plan string) Limits {
+	if limits, ok := l.plans[plan]; ok {
+		return limits
+	}
+	return l.plans[models.PlanFree]
+}
+
+// remainingOf returns what is left of a limit
+func remainingOf(limit, used int64) int64 {
+	if limit == 0 {
+		return Unlimited
+	}
+	return max(limit-used, 0)
+}
+
+// notesKey returns the key counting a user's notes of the day
+func notesKey(userID uuid.UUID, now time.Time) string {
+	return NotesKeyPrefix + userID.String() + ":" + now.UTC().Format(time.DateOnly)
+}
+
+// tokensKey returns the key counting a user's tokens of the month
+func tokensKey(userID uuid.UUID, now time.Time) string {
+	return TokensKeyPrefix + userID.String() + ":" + now.UTC().Format("2006-01")
+}
@@ -3,7 +3,10 @@ package repository
 import (
 	"ai-language-notes/internal/models"
 	"ai-language-notes/internal/storage"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -60,6 +63,9 @@ func (r *NoteRepositoryImpl) CreateNote(note *models.Note) (*models.Note, error)
 func (r *NoteRepositoryImpl) GetNoteByID(id uuid.UUID) (*models.Note, error) {
 	var note models.Note
 	if err := r.db.GetDB().Preload("Tags").First(&note, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoteNotFound
+		}
 		return nil, fmt.Errorf("failed to get note by ID: %w", err)
 	}
 	return &note, nil
@@ -73,15 +79,31 @@ func (r *NoteRepositoryImpl) UpdateNote(note *models.Note) (*models.Note, error)
 	}
 
 	// Update main note fields
-	if err := tx.Model(note).Updates(map[string]interface{}{
+	note.UpdatedAt = time.Now()
+	result := tx.Model(note).Updates(map[string]interface{}{
 		"original_text":     note.OriginalText,
 		"generated_content": note.GeneratedContent,
 		"status":            note.Status,
 		"error_message":     note.ErrorMessage,
+		"attempts":          note.Attempts,
+		"next_retry_at":     note.NextRetryAt,
+		"provider":          note.Provider,
+		"prompt_version":    note.PromptVersion,
+		"model":             note.Usage.Model,
+		"prompt_tokens":     note.Usage.PromptTokens,
+		"completion_tokens": note.Usage.CompletionTokens,
+		"cost_usd":          note.Usage.CostUSD,
+		"analysis":          note.Analysis,
 		"updated_at":        note.UpdatedAt,
-	}).Error; err != nil {
+	})
+	if result.Error != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update note: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Deleted in the meantime, don't resurrect its tags
 		tx.Rollback()
-		return nil, fmt.Errorf("failed to update note: %w", err)
+		return nil, ErrNoteNotFound
 	}
 
 	// Update tags if present
@@ -92,6 +114,13 @@ func (r *NoteRepositoryImpl) UpdateNote(note *models.Note) (*models.Note, error)
 		}
 	}
 
+	if note.Analysis != nil {
+		if err := replaceVocabulary(tx, note); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -104,6 +133,54 @@ func (r *NoteRepositoryImpl) UpdateNote(note *models.Note) (*models.Note, error)
 	return note, nil
 }
 
+// ClaimNote moves a note to processing if it is currently in one of the
+// given statuses. It reports whether this call made the transition, so
+// only one of several racing workers wins the note.
+func (r *NoteRepositoryImpl) ClaimNote(id uuid.UUID, from []models.ProcessingStatus) (bool, error) {
+	result := r.db.GetDB().Model(&models.Note{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(map[string]interface{}{
+			"status":     models.StatusProcessing,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim note: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReleaseNote hands a claimed note back by moving it from processing to
+// pending, so the next delivery of its task can claim it again
+func (r *NoteRepositoryImpl) ReleaseNote(id uuid.UUID) error {
+	result := r.db.GetDB().Model(&models.Note{}).
+		Where("id = ? AND status = ?", id, models.StatusProcessing).
+		Updates(map[string]interface{}{
+			"status":     models.StatusPending,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to release note: %w", result.Error)
+	}
+	return nil
+}
+
+// ResetStaleNote puts a stale note back to pending and clears its scheduled
+// retry. It reports whether the note was reset, which it isn't if it changed
+// since it was loaded, e.g. because a worker claimed it in the meantime.
+func (r *NoteRepositoryImpl) ResetStaleNote(note *models.Note) (bool, error) {
+	result := r.db.GetDB().Model(&models.Note{}).
+		Where("id = ? AND status = ? AND updated_at = ?", note.ID, note.Status, note.UpdatedAt).
+		Updates(map[string]interface{}{
+			"status":        models.StatusPending,
+			"next_retry_at": nil,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to reset note: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // DeleteNote removes a note from the database
 func (r *NoteRepositoryImpl) DeleteNote(id uuid.UUID) error {
 	if err := r.db.GetDB().Delete(&models.Note{}, "id = ?", id).Error; err != nil {
@@ -121,6 +198,23 @@ func (r *NoteRepositoryImpl) GetNotesByUserID(userID uuid.UUID) ([]*models.Note,
 	return notes, nil
 }
 
+// GetStaleNotes retrieves notes in one of the given statuses that haven't
+// been touched since updatedBefore. Notes waiting for a scheduled retry are
+// only included once the retry is overdue by the same margin.
+func (r *NoteRepositoryImpl) GetStaleNotes(statuses []models.ProcessingStatus, updatedBefore time.Time, limit int) ([]*models.Note, error) {
+	var notes []*models.Note
+	err := r.db.GetDB().
+		Where("status IN ? AND updated_at < ?", statuses, updatedBefore).
+		Where("next_retry_at IS NULL OR next_retry_at < ?", updatedBefore).
+		Order("updated_at").
+		Limit(limit).
+		Find(&notes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale notes: %w", err)
+	}
+	return notes, nil
+}
+
 // FindOrCreateTags finds existing tags or creates new ones
 func (r *NoteRepositoryImpl) FindOrCreateTags(tagNames []string) ([]models.Tag, error) {
 	var tags []models.Tag
@@ -143,3 +237,85 @@ func (r *NoteRepositoryImpl) AddTagsToNote(noteID uuid.UUID, tags []models.Tag)
 	}
 	return nil
 }
+
+// GetVocabulary retrieves a page of a user's vocabulary, one item per lemma
+// and part of speech, most recently seen first, and the total count
+func (r *NoteRepositoryImpl) GetVocabulary(userID uuid.UUID, filter VocabularyFilter, offset, limit int) ([]*models.VocabularyItem, int64, error) {
+	query := r.db.GetDB().Model(&models.NoteVocabularyEntry{}).Where("user_id = ?", userID)
+	if filter.LemmaPrefix != "" {
+		query = query.Where("lemma LIKE ?", escapeLike(strings.ToLower(filter.LemmaPrefix))+"%")
+	}
+	if filter.PartOfSpeech != "" {
+		query = query.Where("part_of_speech = ?", filter.PartOfSpeech)
+	}
+	query = query.Group("lemma, part_of_speech").Session(&gorm.Session{})
+
+	var total int64
+	if err := r.db.GetDB().Table("(?) AS vocabulary", query.Select("lemma")).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count vocabulary: %w", err)
+	}
+
+	var items []*models.VocabularyItem
+	err := query.
+		Select("lemma, part_of_speech, MAX(translation) AS translation, COUNT(DISTINCT note_id) AS note_count, MAX(created_at) AS last_seen_at").
+		Order("last_seen_at DESC, lemma").
+		Offset(offset).
+		Limit(limit).
+		Scan(&items).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get vocabulary: %w", err)
+	}
+	return items, total, nil
+}
+
+// GetNotesByLemma retrieves a user's notes whose analysis contains a lemma
+func (r *NoteRepositoryImpl) GetNotesByLemma(userID uuid.UUID, lemma string) ([]*models.Note, error) {
+	var notes []*models.Note
+	err := r.db.GetDB().Preload("Tags").
+		Where("user_id = ?", userID).
+		Where("id IN (?)", r.db.GetDB().Model(&models.NoteVocabularyEntry{}).
+			Select("note_id").
+			Where("user_id = ? AND lemma = ?", userID, strings.ToLower(lemma))).
+		Order("created_at DESC").
+		Find(&notes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes by lemma: %w", err)
+	}
+	return notes, nil
+}
+
+// replaceVocabulary replaces a note's vocabulary entries with those of its analysis
+func replaceVocabulary(tx *gorm.DB, note *models.Note) error {
+	if err := tx.Where("note_id = ?", note.ID).Delete(&models.NoteVocabularyEntry{}).Error; err != nil {
+		return fmt.Errorf("failed to clear vocabulary: %w", err)
+	}
+
+	var entries []models.NoteVocabularyEntry
+	for _, entry := range note.Analysis.Vocabulary {
+		lemma := strings.ToLower(strings.TrimSpace(entry.Lemma))
+		if lemma == "" {
+			continue
+		}
+		entries = append(entries, models.NoteVocabularyEntry{
+			NoteID:       note.ID,
+			UserID:       note.UserID,
+			Term:         entry.Term,
+			Lemma:        lemma,
+			PartOfSpeech: entry.PartOfSpeech,
+			Translation:  entry.Translation,
+		})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := tx.Omit("Note").Create(&entries).Error; err != nil {
+		return fmt.Errorf("failed to save vocabulary: %w", err)
+	}
+	return nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
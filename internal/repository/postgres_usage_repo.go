@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/storage"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRepositoryImpl implements UsageRepository
+type UsageRepositoryImpl struct {
+	db *storage.PostgresStore
+}
+
+// NewUsageRepository creates a new UsageRepository
+func NewUsageRepository(db *storage.PostgresStore) UsageRepository {
+	return &UsageRepositoryImpl{db: db}
+}
+
+// RecordUsage adds one processed request to the user's usage of the day
+func (r *UsageRepositoryImpl) RecordUsage(userID uuid.UUID, day time.Time, usage models.TokenUsage) error {
+	row := &models.UsageDaily{
+		UserID:           userID,
+		Day:              day.UTC().Truncate(24 * time.Hour),
+		Model:            usage.Model,
+		Requests:         1,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          usage.CostUSD,
+		UpdatedAt:        time.Now(),
+	}
+
+	err := r.db.GetDB().Omit("User").Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "model"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":          gorm.Expr("usage_daily.requests + EXCLUDED.requests"),
+			"prompt_tokens":     gorm.Expr("usage_daily.prompt_tokens + EXCLUDED.prompt_tokens"),
+			"completion_tokens": gorm.Expr("usage_daily.completion_tokens + EXCLUDED.completion_tokens"),
+			"cost_usd":          gorm.Expr("usage_daily.cost_usd + EXCLUDED.cost_usd"),
+			"updated_at":        gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(row).Error
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// GetDailyUsage retrieves a user's usage between two days, both included,
+// ordered by day and model
+func (r *UsageRepositoryImpl) GetDailyUsage(userID uuid.UUID, from, to time.Time) ([]*models.UsageDaily, error) {
+	var rows []*models.UsageDaily
+	err := r.db.GetDB().
+		Where("user_id = ? AND day BETWEEN ? AND ?", userID, from.Format(time.DateOnly), to.Format(time.DateOnly)).
+		Order("day, model").
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookRepositoryImpl implements WebhookRepository
+type WebhookRepositoryImpl struct {
+	db *storage.PostgresStore
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *storage.PostgresStore) WebhookRepository {
+	return &WebhookRepositoryImpl{db: db}
+}
+
+// CreateWebhook creates a new webhook in the database
+func (r *WebhookRepositoryImpl) CreateWebhook(webhook *models.Webhook) (*models.Webhook, error) {
+	if err := r.db.GetDB().Create(webhook).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// GetWebhookByID retrieves a webhook by its ID
+func (r *WebhookRepositoryImpl) GetWebhookByID(id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.GetDB().First(&webhook, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook by ID: %w", err)
+	}
+	return &webhook, nil
+}
+
+// GetWebhooksByUserID retrieves all webhooks of a user
+func (r *WebhookRepositoryImpl) GetWebhooksByUserID(userID uuid.UUID) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if err := r.db.GetDB().Where("user_id = ?", userID).Order("created_at").Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhooks by user ID: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetActiveWebhooksForEvent retrieves the active webhooks of a user that
+// subscribed to the event type
+func (r *WebhookRepositoryImpl) GetActiveWebhooksForEvent(userID uuid.UUID, eventType string) ([]*models.Webhook, error) {
+	eventJSON, err := json.Marshal([]string{eventType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event type: %w", err)
+	}
+
+	var webhooks []*models.Webhook
+	err = r.db.GetDB().
+		Where("user_id = ? AND active AND events @> ?::jsonb", userID, string(eventJSON)).
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks for event: %w", err)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook updates an existing webhook
+func (r *WebhookRepositoryImpl) UpdateWebhook(webhook *models.Webhook) (*models.Webhook, error) {
+	webhook.UpdatedAt = time.Now()
+	if err := r.db.GetDB().Select("url", "events", "active", "updated_at").Updates(webhook).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (r *WebhookRepositoryImpl) DeleteWebhook(id uuid.UUID) error {
+	err := r.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.WebhookDelivery{}, "webhook_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, "id = ?", id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// CreateDelivery queues a delivery
+func (r *WebhookRepositoryImpl) CreateDelivery(delivery *models.WebhookDelivery) error {
+	if err := r.db.GetDB().Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries that are due.
+// Their next attempt is pushed back by lease, so another dispatcher only
+// picks them up again if this one dies before recording the outcome.
+func (r *WebhookRepositoryImpl) ClaimDueDeliveries(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := r.db.GetDB().Raw(`
+		UPDATE webhook_deliveries
+		SET next_attempt_at = now() + make_interval(secs => ?), updated_at = now()
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT ?
+		)
+		RETURNING *`,
+		lease.Seconds(), models.DeliveryPending, limit,
+	).Scan(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery records the outcome of a delivery attempt
+func (r *WebhookRepositoryImpl) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	err := r.db.GetDB().Model(delivery).Updates(map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+		"delivered_at":    delivery.DeliveredAt,
+		"updated_at":      delivery.UpdatedAt,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDeliveriesByWebhookID returns a page of a webhook's deliveries, newest
+// first, and the total count
+func (r *WebhookRepositoryImpl) GetDeliveriesByWebhookID(webhookID uuid.UUID, offset, limit int) ([]*models.WebhookDelivery, int64, error) {
+	db := r.db.GetDB().Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, total, nil
+}
@@ -2,10 +2,20 @@ package repository
 
 import (
 	"ai-language-notes/internal/models"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// Repository errors
+var (
+	// ErrNoteNotFound is returned when a note doesn't exist (anymore)
+	ErrNoteNotFound = errors.New("note not found")
+	// ErrWebhookNotFound is returned when a webhook doesn't exist
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
 type UserRepository interface {
 	CreateUser(user *models.User) (*models.User, error)
 	GetUserByID(id uuid.UUID) (*models.User, error)
@@ -20,8 +30,38 @@ type NoteRepository interface {
 	CreateNote(note *models.Note) (*models.Note, error)
 	GetNoteByID(id uuid.UUID) (*models.Note, error)
 	UpdateNote(note *models.Note) (*models.Note, error)
+	ClaimNote(id uuid.UUID, from []models.ProcessingStatus) (bool, error)
+	ReleaseNote(id uuid.UUID) error
+	ResetStaleNote(note *models.Note) (bool, error)
 	DeleteNote(id uuid.UUID) error
 	GetNotesByUserID(userID uuid.UUID) ([]*models.Note, error)
+	GetStaleNotes(statuses []models.ProcessingStatus, updatedBefore time.Time, limit int) ([]*models.Note, error)
 	FindOrCreateTags(tagNames []string) ([]models.Tag, error)
 	AddTagsToNote(noteID uuid.UUID, tags []models.Tag) error
+	GetVocabulary(userID uuid.UUID, filter VocabularyFilter, offset, limit int) ([]*models.VocabularyItem, int64, error)
+	GetNotesByLemma(userID uuid.UUID, lemma string) ([]*models.Note, error)
+}
+
+// VocabularyFilter narrows down a vocabulary query. Empty fields match everything.
+type VocabularyFilter struct {
+	LemmaPrefix  string
+	PartOfSpeech string
+}
+
+type WebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) (*models.Webhook, error)
+	GetWebhookByID(id uuid.UUID) (*models.Webhook, error)
+	GetWebhooksByUserID(userID uuid.UUID) ([]*models.Webhook, error)
+	GetActiveWebhooksForEvent(userID uuid.UUID, eventType string) ([]*models.Webhook, error)
+	UpdateWebhook(webhook *models.Webhook) (*models.Webhook, error)
+	DeleteWebhook(id uuid.UUID) error
+	CreateDelivery(delivery *models.WebhookDelivery) error
+	ClaimDueDeliveries(limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	GetDeliveriesByWebhookID(webhookID uuid.UUID, offset, limit int) ([]*models.WebhookDelivery, int64, error)
+}
+
+type UsageRepository interface {
+	RecordUsage(userID uuid.UUID, day time.Time, usage models.TokenUsage) error
+	GetDailyUsage(userID uuid.UUID, from, to time.Time) ([]*models.UsageDaily, error)
 }
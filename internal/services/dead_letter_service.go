@@ -0,0 +1,128 @@
+package services
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/repository"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// DeadLetterService defines the interface for inspecting and recovering
+// tasks the worker gave up on
+type DeadLetterService interface {
+	ListDeadLetters(ctx context.Context, offset, limit int) ([]*queue.DeadLetter, int64, error)
+	GetDeadLetter(ctx context.Context, id uuid.UUID) (*queue.DeadLetter, error)
+	RequeueDeadLetter(ctx context.Context, id uuid.UUID) error
+	DeleteDeadLetter(ctx context.Context, id uuid.UUID) error
+	PurgeDeadLetters(ctx context.Context) (int64, error)
+}
+
+// deadLetterService implements the DeadLetterService interface
+type deadLetterService struct {
+	deadLetters queue.DeadLetterStore
+	noteRepo    repository.NoteRepository
+}
+
+// NewDeadLetterService creates a new DeadLetterService instance
+func NewDeadLetterService(deadLetters queue.DeadLetterStore, noteRepo repository.NoteRepository) DeadLetterService {
+	return &deadLetterService{
+		deadLetters: deadLetters,
+		noteRepo:    noteRepo,
+	}
+}
+
+// ListDeadLetters returns a page of dead letters and the total count
+func (s *deadLetterService) ListDeadLetters(ctx context.Context, offset, limit int) ([]*queue.DeadLetter, int64, error) {
+	return s.deadLetters.ListDeadLetters(ctx, offset, limit)
+}
+
+// GetDeadLetter retrieves a single dead letter
+func (s *deadLetterService) GetDeadLetter(ctx context.Context, id uuid.UUID) (*queue.DeadLetter, error) {
+	entry, err := s.deadLetters.GetDeadLetter(ctx, id)
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		return nil, ErrNotFound
+	}
+	return entry, err
+}
+
+// RequeueDeadLetter resets a dead-lettered task's note so the worker
+// processes it from scratch and puts the task back on the queue. The note
+// is reset first so the worker can claim it as soon as the task is queued,
+// and restored if the task can't be requeued.
+func (s *deadLetterService) RequeueDeadLetter(ctx context.Context, id uuid.UUID) error {
+	entry, err := s.deadLetters.GetDeadLetter(ctx, id)
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	task, err := entry.Task()
+	if err != nil {
+		return err
+	}
+
+	// A deleted note needs no reset, the worker will skip the task
+	note, err := s.noteRepo.GetNoteByID(task.NoteID)
+	if err != nil && !errors.Is(err, repository.ErrNoteNotFound) {
+		return err
+	}
+	var previous models.Note
+	if note != nil {
+		previous = *note
+		note.Status = models.StatusPending
+		note.ErrorMessage = ""
+		note.Attempts = 0
+		note.NextRetryAt = nil
+		if _, err := s.noteRepo.UpdateNote(note); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.deadLetters.RequeueDeadLetter(ctx, id)
+	if err == nil {
+		return nil
+	}
+	if note != nil {
+		s.restoreNote(&previous)
+	}
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// restoreNote undoes the reset of a note whose task couldn't be requeued
+func (s *deadLetterService) restoreNote(previous *models.Note) {
+	note, err := s.noteRepo.GetNoteByID(previous.ID)
+	if err != nil || note.Status != models.StatusPending {
+		// Gone or already picked up by someone else
+		return
+	}
+
+	note.Status = previous.Status
+	note.ErrorMessage = previous.ErrorMessage
+	note.Attempts = previous.Attempts
+	note.NextRetryAt = previous.NextRetryAt
+	if _, err := s.noteRepo.UpdateNote(note); err != nil {
+		log.Printf("Failed to restore note %s after failed requeue: %v", note.ID, err)
+	}
+}
+
+// DeleteDeadLetter permanently removes a dead letter
+func (s *deadLetterService) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	err := s.deadLetters.DeleteDeadLetter(ctx, id)
+	if errors.Is(err, queue.ErrDeadLetterNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// PurgeDeadLetters removes every dead letter
+func (s *deadLetterService) PurgeDeadLetters(ctx context.Context) (int64, error) {
+	return s.deadLetters.PurgeDeadLetters(ctx)
+}
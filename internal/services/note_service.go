@@ -2,16 +2,27 @@ package services
 
 import (
 	"ai-language-notes/internal/ai"
+	"ai-language-notes/internal/events"
 	"ai-language-notes/internal/models"
 	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/quota"
 	"ai-language-notes/internal/repository"
+	"ai-language-notes/internal/webhook"
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// streamProcessingTimeout bounds streamed processing like the workers bound theirs
+const streamProcessingTimeout = 3 * time.Minute
+
+// errNoteDeleted is the cancellation cause of streamed processing whose note
+// was deleted
+var errNoteDeleted = errors.New("note was deleted")
+
 // Common service errors
 var (
 	ErrNotAuthorized = errors.New("not authorized to access this resource")
@@ -21,35 +32,51 @@ var (
 // NoteService defines the interface for note-related business logic
 type NoteService interface {
 	CreateNote(ctx context.Context, userID uuid.UUID, originalText string) (*models.Note, error)
+	CreateNoteStream(ctx context.Context, userID uuid.UUID, originalText string, onDelta func(string)) (*models.Note, error)
+	ImportNotes(ctx context.Context, userID uuid.UUID, originalTexts []string) ([]*models.Note, error)
 	GetNoteByID(noteID uuid.UUID, userID uuid.UUID) (*models.Note, error)
 	GetNotesByUserID(userID uuid.UUID) ([]*models.Note, error)
 	DeleteNote(noteID uuid.UUID, userID uuid.UUID) error
+	SubscribeNoteEvents(ctx context.Context, userID uuid.UUID) (events.Subscription, error)
 }
 
 // NoteServiceImpl implements the NoteService interface
 type NoteServiceImpl struct {
 	noteRepo     repository.NoteRepository
 	userRepo     repository.UserRepository
+	usageRepo    repository.UsageRepository
+	quotas       quota.Limiter // nil disables quotas
 	llmService   ai.LLMService
-	queueService *queue.QueueService
+	queueService queue.Queue
+	bus          events.Bus
+	webhooks     *webhook.Dispatcher
 }
 
 // NewNoteService creates a new instance of NoteService
 func NewNoteService(
 	noteRepo repository.NoteRepository,
 	userRepo repository.UserRepository,
+	usageRepo repository.UsageRepository,
+	quotas quota.Limiter,
 	llmService ai.LLMService,
-	queueService *queue.QueueService,
+	queueService queue.Queue,
+	bus events.Bus,
+	webhooks *webhook.Dispatcher,
 ) NoteService {
 	return &NoteServiceImpl{
 		noteRepo:     noteRepo,
 		userRepo:     userRepo,
+		usageRepo:    usageRepo,
+		quotas:       quotas,
 		llmService:   llmService,
 		queueService: queueService,
+		bus:          bus,
+		webhooks:     webhooks,
 	}
 }
 
-// CreateNote handles the business logic for creating a new note
+// CreateNote handles the business logic for creating a new note. It fails
+// with a *quota.ExceededError once the user's plan doesn't allow more notes.
 func (s *NoteServiceImpl) CreateNote(ctx context.Context, userID uuid.UUID, originalText string) (*models.Note, error) {
 	// Get user's language preferences
 	user, err := s.userRepo.GetUserByID(userID)
@@ -57,12 +84,230 @@ func (s *NoteServiceImpl) CreateNote(ctx context.Context, userID uuid.UUID, orig
 		return nil, err
 	}
 
+	if err := s.reserveNotes(ctx, user, 1); err != nil {
+		return nil, err
+	}
+
+	note, err := s.createNote(ctx, user, originalText, queue.PriorityInteractive)
+	if note == nil {
+		s.releaseNotes(user, 1)
+	}
+	return note, err
+}
+
+// CreateNoteStream creates a note and processes it right away instead of
+// queueing it, passing the LLM's output to onDelta as it is generated. If
+// processing fails the note is handed to the queue like any other note and
+// returned along with the error. Deleting the note aborts processing and
+// fails with ErrNotFound.
+func (s *NoteServiceImpl) CreateNoteStream(ctx context.Context, userID uuid.UUID, originalText string, onDelta func(string)) (*models.Note, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveNotes(ctx, user, 1); err != nil {
+		return nil, err
+	}
+
+	// Listen for deletion before creating the note so it can't slip through
+	noteID := uuid.New()
+	noteCtx, unwatch := s.watchCancellation(ctx, noteID)
+	defer unwatch()
+
+	note, err := s.noteRepo.CreateNote(&models.Note{
+		ID:           noteID,
+		UserID:       user.ID,
+		OriginalText: originalText,
+		Status:       models.StatusProcessing,
+		InputFlags:   detectInjection(originalText),
+	})
+	if err != nil {
+		s.releaseNotes(user, 1)
+		return nil, err
+	}
+	s.publishStatus(note)
+
+	llmCtx, cancel := context.WithTimeout(noteCtx, streamProcessingTimeout)
+	defer cancel()
+
+	processedContent, err := s.llmService.ProcessTextStream(llmCtx, originalText, user.NativeLanguage, user.TargetLanguage, onDelta)
+
+	// The tokens are spent whether or not the note can be completed
+	usage := ai.UsageOf(err)
+	if err == nil {
+		usage = processedContent.AllUsage()
+	}
+	s.recordUsage(user.ID, usage)
+
+	if errors.Is(context.Cause(noteCtx), errNoteDeleted) {
+		log.Printf("Dropped streamed note %s: deleted during processing", note.ID)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Printf("Streaming LLM processing failed for note %s, queueing it: %v", note.ID, err)
+		return note, errors.Join(err, s.queueStreamedNote(ctx, user, note))
+	}
+
+	note.GeneratedContent = processedContent.Content
+	note.Provider = processedContent.Provider
+	note.PromptVersion = processedContent.PromptVersion
+	note.Analysis = processedContent.Analysis
+	note.Usage = processedContent.Usage
+	note.Status = models.StatusCompleted
+	note.ErrorMessage = ""
+	note.Attempts = 1
+
+	var tags []models.Tag
+	for _, tagName := range processedContent.Tags {
+		tags = append(tags, models.Tag{
+			Name: tagName,
+		})
+	}
+	note.Tags = tags
+
+	if _, err := s.noteRepo.UpdateNote(note); err != nil {
+		if errors.Is(err, repository.ErrNoteNotFound) {
+			return nil, ErrNotFound // Deleted while streaming
+		}
+		return note, err
+	}
+	s.publishStatus(note)
+
+	return note, nil
+}
+
+// watchCancellation returns a context that is cancelled with errNoteDeleted
+// once the note is deleted, like the workers' tracked notes, and a func to
+// stop watching
+func (s *NoteServiceImpl) watchCancellation(ctx context.Context, noteID uuid.UUID) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	sub, err := s.bus.Subscribe(ctx, events.NoteCancelTopic)
+	if err != nil {
+		log.Printf("Failed to subscribe to note cancellations, note %s will finish processing if deleted: %v", noteID, err)
+		return ctx, func() { cancel(nil) }
+	}
+
+	go func() {
+		for msg := range sub.Messages() {
+			if id, err := uuid.ParseBytes(msg.Payload); err == nil && id == noteID {
+				log.Printf("Cancelling streamed processing of deleted note %s", noteID)
+				cancel(errNoteDeleted)
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		cancel(nil)
+		sub.Close()
+	}
+}
+
+// recordUsage adds the LLM usage of an attempt to the user's daily totals,
+// per model, and token quota like the workers do. Failures are only logged.
+func (s *NoteServiceImpl) recordUsage(userID uuid.UUID, usages []models.TokenUsage) {
+	var tokens int64
+	for _, usage := range usages {
+		if usage.Model == "" {
+			continue
+		}
+		if err := s.usageRepo.RecordUsage(userID, time.Now(), usage); err != nil {
+			log.Printf("Failed to record LLM usage of user %s: %v", userID, err)
+		}
+		tokens += usage.PromptTokens + usage.CompletionTokens
+	}
+
+	if s.quotas != nil && tokens > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.quotas.RecordTokens(ctx, userID, tokens); err != nil {
+			log.Printf("Failed to count tokens of user %s against their quota: %v", userID, err)
+		}
+	}
+}
+
+// queueStreamedNote hands a note whose streamed processing failed to the
+// workers. The request's context may be cancelled, it is only consulted for
+// request options.
+func (s *NoteServiceImpl) queueStreamedNote(reqCtx context.Context, user *models.User, note *models.Note) error {
+	note.Status = models.StatusPending
+	if _, err := s.noteRepo.UpdateNote(note); err != nil {
+		return err
+	}
+	s.publishStatus(note)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := s.queueService.EnqueueTask(ctx, &queue.LLMProcessingTask{
+		NoteID:         note.ID,
+		OriginalText:   note.OriginalText,
+		UserID:         user.ID,
+		NativeLanguage: user.NativeLanguage,
+		TargetLanguage: user.TargetLanguage,
+		CreatedAt:      time.Now(),
+		Priority:       queue.PriorityInteractive,
+		BypassCache:    ai.CacheBypassed(reqCtx),
+	})
+	return err
+}
+
+// publishStatus announces a note's status like the workers do, including to
+// webhooks once it is final
+func (s *NoteServiceImpl) publishStatus(note *models.Note) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := events.PublishNoteEvent(ctx, s.bus, note); err != nil {
+		log.Printf("Failed to publish status of note %s: %v", note.ID, err)
+	}
+
+	if s.webhooks != nil {
+		if err := s.webhooks.NotifyNote(note); err != nil {
+			log.Printf("Failed to queue webhooks for note %s: %v", note.ID, err)
+		}
+	}
+}
+
+// ImportNotes creates notes in bulk. Their processing is scheduled in the
+// bulk lane so large imports don't delay notes created interactively. An
+// import that doesn't fit in the user's quota is rejected as a whole.
+func (s *NoteServiceImpl) ImportNotes(ctx context.Context, userID uuid.UUID, originalTexts []string) ([]*models.Note, error) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveNotes(ctx, user, len(originalTexts)); err != nil {
+		return nil, err
+	}
+
+	notes := make([]*models.Note, 0, len(originalTexts))
+	for _, originalText := range originalTexts {
+		note, err := s.createNote(ctx, user, originalText, queue.PriorityBulk)
+		if note != nil {
+			notes = append(notes, note)
+		}
+		if err != nil {
+			s.releaseNotes(user, len(originalTexts)-len(notes))
+			return notes, err
+		}
+	}
+
+	return notes, nil
+}
+
+// createNote saves a pending note and enqueues it in the given lane
+func (s *NoteServiceImpl) createNote(ctx context.Context, user *models.User, originalText string, priority queue.Priority) (*models.Note, error) {
 	// Create a new note with pending status
 	newNote := &models.Note{
 		ID:           uuid.New(),
-		UserID:       userID,
+		UserID:       user.ID,
 		OriginalText: originalText,
 		Status:       models.StatusPending,
+		InputFlags:   detectInjection(originalText),
 	}
 
 	// Save the note to the database
@@ -75,20 +320,65 @@ func (s *NoteServiceImpl) CreateNote(ctx context.Context, userID uuid.UUID, orig
 	task := &queue.LLMProcessingTask{
 		NoteID:         savedNote.ID,
 		OriginalText:   savedNote.OriginalText,
-		UserID:         userID,
+		UserID:         user.ID,
 		NativeLanguage: user.NativeLanguage,
 		TargetLanguage: user.TargetLanguage,
 		CreatedAt:      time.Now(),
+		Priority:       priority,
+		BypassCache:    ai.CacheBypassed(ctx),
 	}
 
 	// Enqueue the task for processing
-	if err = s.queueService.EnqueueTask(ctx, task); err != nil {
+	if _, err = s.queueService.EnqueueTask(ctx, task); err != nil {
 		return savedNote, err // Return note even if queueing fails
 	}
 
 	return savedNote, nil
 }
 
+// reserveNotes counts notes against the user's quota, failing with a
+// *quota.ExceededError if their plan doesn't allow them. Quotas aren't
+// enforced while the limiter is unavailable.
+func (s *NoteServiceImpl) reserveNotes(ctx context.Context, user *models.User, count int) error {
+	if s.quotas == nil {
+		return nil
+	}
+
+	_, err := s.quotas.ReserveNotes(ctx, user.ID, user.Plan, int64(count))
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Failed to check quota of user %s, allowing the notes: %v", user.ID, err)
+	}
+	return nil
+}
+
+// releaseNotes gives back notes reserved for notes that weren't created
+func (s *NoteServiceImpl) releaseNotes(user *models.User, count int) {
+	if s.quotas == nil || count <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.quotas.ReleaseNotes(ctx, user.ID, int64(count)); err != nil {
+		log.Printf("Failed to release quota of user %s: %v", user.ID, err)
+	}
+}
+
+// detectInjection flags text that looks like a prompt injection attempt. The
+// note is processed anyway; the flags let clients and admins review it.
+func detectInjection(originalText string) []string {
+	flags := ai.DetectInjection(originalText)
+	if len(flags) > 0 {
+		log.Printf("Note text flagged as possible prompt injection: %v", flags)
+	}
+	return flags
+}
+
 // GetNoteByID retrieves a specific note and verifies ownership
 func (s *NoteServiceImpl) GetNoteByID(noteID uuid.UUID, userID uuid.UUID) (*models.Note, error) {
 	note, err := s.noteRepo.GetNoteByID(noteID)
@@ -109,7 +399,8 @@ func (s *NoteServiceImpl) GetNotesByUserID(userID uuid.UUID) ([]*models.Note, er
 	return s.noteRepo.GetNotesByUserID(userID)
 }
 
-// DeleteNote deletes a note after verifying ownership
+// DeleteNote deletes a note after verifying ownership and cancels any
+// processing of it that is in progress
 func (s *NoteServiceImpl) DeleteNote(noteID uuid.UUID, userID uuid.UUID) error {
 	note, err := s.noteRepo.GetNoteByID(noteID)
 	if err != nil {
@@ -121,5 +412,24 @@ func (s *NoteServiceImpl) DeleteNote(noteID uuid.UUID, userID uuid.UUID) error {
 		return ErrNotAuthorized
 	}
 
-	return s.noteRepo.DeleteNote(noteID)
+	if err := s.noteRepo.DeleteNote(noteID); err != nil {
+		return err
+	}
+
+	// Only notes that haven't finished can have an LLM request in flight
+	if note.Status == models.StatusPending || note.Status == models.StatusProcessing {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.bus.Publish(ctx, events.NoteCancelTopic, []byte(noteID.String())); err != nil {
+			log.Printf("Failed to publish cancellation for note %s: %v", noteID, err)
+		}
+	}
+
+	return nil
+}
+
+// SubscribeNoteEvents streams status changes of the user's notes. The
+// caller must close the subscription.
+func (s *NoteServiceImpl) SubscribeNoteEvents(ctx context.Context, userID uuid.UUID) (events.Subscription, error) {
+	return s.bus.Subscribe(ctx, events.UserNotesTopic(userID))
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/repository"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxUsageRange is the longest range of days usage can be queried for
+const MaxUsageRange = 366 * 24 * time.Hour
+
+// ErrInvalidUsageRange is returned when a usage range is reversed or too long
+var ErrInvalidUsageRange = errors.New("invalid date range: from must not be after to, and the range is limited to 366 days")
+
+// UsageService defines the interface for querying a user's LLM usage
+type UsageService interface {
+	GetDailyUsage(userID uuid.UUID, from, to time.Time) ([]*models.UsageDaily, error)
+}
+
+// usageService implements the UsageService interface
+type usageService struct {
+	usageRepo repository.UsageRepository
+}
+
+// NewUsageService creates a new UsageService instance
+func NewUsageService(usageRepo repository.UsageRepository) UsageService {
+	return &usageService{
+		usageRepo: usageRepo,
+	}
+}
+
+// GetDailyUsage returns the user's usage per day and model between two
+// days, both included
+func (s *usageService) GetDailyUsage(userID uuid.UUID, from, to time.Time) ([]*models.UsageDaily, error) {
+	if from.After(to) || to.Sub(from) >= MaxUsageRange {
+		return nil, ErrInvalidUsageRange
+	}
+	return s.usageRepo.GetDailyUsage(userID, from, to)
+}
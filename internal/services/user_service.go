@@ -3,14 +3,20 @@ package services
 import (
 	"ai-language-notes/internal/models"
 	"ai-language-notes/internal/repository"
+	"errors"
+	"slices"
 
 	"github.com/google/uuid"
 )
 
+// ErrInvalidPlan is returned when assigning a plan tier that doesn't exist
+var ErrInvalidPlan = errors.New("unknown plan")
+
 // UserService defines the interface for user-related business logic
 type UserService interface {
 	GetUserByID(userID uuid.UUID) (*models.User, error)
 	UpdateUserProfile(user *models.User, nativeLanguage *string, targetLanguage *string) (*models.User, error)
+	UpdatePlan(userID uuid.UUID, plan string) (*models.User, error)
 }
 
 // userService implements the UserService interface
@@ -41,3 +47,19 @@ func (s *userService) UpdateUserProfile(user *models.User, nativeLanguage *strin
 
 	return s.userRepo.UpdateUser(user)
 }
+
+// UpdatePlan moves a user to another plan tier, which applies to their
+// quotas right away
+func (s *userService) UpdatePlan(userID uuid.UUID, plan string) (*models.User, error) {
+	if !slices.Contains(models.Plans, plan) {
+		return nil, ErrInvalidPlan
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	user.Plan = plan
+	return s.userRepo.UpdateUser(user)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/repository"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPartOfSpeech is returned when filtering by an unknown part of speech
+var ErrInvalidPartOfSpeech = errors.New("unknown part of speech")
+
+// VocabularyService defines the interface for querying a user's vocabulary
+// across the analyses of their notes
+type VocabularyService interface {
+	GetVocabulary(userID uuid.UUID, lemmaPrefix, partOfSpeech string, offset, limit int) ([]*models.VocabularyItem, int64, error)
+	GetNotesByLemma(userID uuid.UUID, lemma string) ([]*models.Note, error)
+}
+
+// vocabularyService implements the VocabularyService interface
+type vocabularyService struct {
+	noteRepo repository.NoteRepository
+}
+
+// NewVocabularyService creates a new VocabularyService instance
+func NewVocabularyService(noteRepo repository.NoteRepository) VocabularyService {
+	return &vocabularyService{
+		noteRepo: noteRepo,
+	}
+}
+
+// GetVocabulary returns a page of the user's vocabulary, optionally narrowed
+// down to lemmas starting with a prefix and to a part of speech
+func (s *vocabularyService) GetVocabulary(userID uuid.UUID, lemmaPrefix, partOfSpeech string, offset, limit int) ([]*models.VocabularyItem, int64, error) {
+	if partOfSpeech != "" && !slices.Contains(models.PartsOfSpeech, partOfSpeech) {
+		return nil, 0, ErrInvalidPartOfSpeech
+	}
+
+	filter := repository.VocabularyFilter{
+		LemmaPrefix:  strings.TrimSpace(lemmaPrefix),
+		PartOfSpeech: partOfSpeech,
+	}
+	return s.noteRepo.GetVocabulary(userID, filter, offset, limit)
+}
+
+// GetNotesByLemma returns the user's notes whose analysis contains a lemma
+func (s *vocabularyService) GetNotesByLemma(userID uuid.UUID, lemma string) ([]*models.Note, error) {
+	return s.noteRepo.GetNotesByLemma(userID, strings.TrimSpace(lemma))
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/repository"
+	"ai-language-notes/internal/webhook"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook validation errors
+var (
+	ErrInvalidWebhookURL     = errors.New("webhook URL must be an absolute http or https URL")
+	ErrWebhookHostNotAllowed = errors.New("webhook URL must point to a public host")
+	ErrInvalidWebhookEvent   = errors.New("unknown webhook event type")
+)
+
+// WebhookService defines the interface for managing a user's webhooks
+type WebhookService interface {
+	CreateWebhook(userID uuid.UUID, rawURL string, eventTypes []string, secret string) (*models.Webhook, error)
+	GetWebhooks(userID uuid.UUID) ([]*models.Webhook, error)
+	GetWebhook(webhookID uuid.UUID, userID uuid.UUID) (*models.Webhook, error)
+	UpdateWebhook(webhookID uuid.UUID, userID uuid.UUID, rawURL *string, eventTypes []string, active *bool) (*models.Webhook, error)
+	DeleteWebhook(webhookID uuid.UUID, userID uuid.UUID) error
+	GetDeliveries(webhookID uuid.UUID, userID uuid.UUID, offset, limit int) ([]*models.WebhookDelivery, int64, error)
+}
+
+// webhookService implements the WebhookService interface
+type webhookService struct {
+	webhookRepo          repository.WebhookRepository
+	allowPrivateNetworks bool
+}
+
+// NewWebhookService creates a new WebhookService instance. Unless
+// allowPrivateNetworks is set, webhook URLs must resolve to public addresses.
+func NewWebhookService(webhookRepo repository.WebhookRepository, allowPrivateNetworks bool) WebhookService {
+	return &webhookService{
+		webhookRepo:          webhookRepo,
+		allowPrivateNetworks: allowPrivateNetworks,
+	}
+}
+
+// CreateWebhook registers a webhook. A random secret is generated if none is given.
+func (s *webhookService) CreateWebhook(userID uuid.UUID, rawURL string, eventTypes []string, secret string) (*models.Webhook, error) {
+	if err := s.validateWebhookURL(rawURL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEvents(eventTypes); err != nil {
+		return nil, err
+	}
+
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	return s.webhookRepo.CreateWebhook(&models.Webhook{
+		UserID: userID,
+		URL:    rawURL,
+		Secret: secret,
+		Events: eventTypes,
+		Active: true,
+	})
+}
+
+// GetWebhooks retrieves all webhooks of a user
+func (s *webhookService) GetWebhooks(userID uuid.UUID) ([]*models.Webhook, error) {
+	return s.webhookRepo.GetWebhooksByUserID(userID)
+}
+
+// GetWebhook retrieves a webhook and verifies ownership
+func (s *webhookService) GetWebhook(webhookID uuid.UUID, userID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.GetWebhookByID(webhookID)
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the webhook belongs to the user
+	if webhook.UserID != userID {
+		return nil, ErrNotAuthorized
+	}
+
+	return webhook, nil
+}
+
+// UpdateWebhook changes the URL, event types or active flag of a webhook
+func (s *webhookService) UpdateWebhook(webhookID uuid.UUID, userID uuid.UUID, rawURL *string, eventTypes []string, active *bool) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawURL != nil {
+		if err := s.validateWebhookURL(*rawURL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *rawURL
+	}
+	if eventTypes != nil {
+		if err := validateWebhookEvents(eventTypes); err != nil {
+			return nil, err
+		}
+		webhook.Events = eventTypes
+	}
+	if active != nil {
+		webhook.Active = *active
+	}
+
+	return s.webhookRepo.UpdateWebhook(webhook)
+}
+
+// DeleteWebhook deletes a webhook after verifying ownership
+func (s *webhookService) DeleteWebhook(webhookID uuid.UUID, userID uuid.UUID) error {
+	if _, err := s.GetWebhook(webhookID, userID); err != nil {
+		return err
+	}
+	return s.webhookRepo.DeleteWebhook(webhookID)
+}
+
+// GetDeliveries returns a page of a webhook's delivery log
+func (s *webhookService) GetDeliveries(webhookID uuid.UUID, userID uuid.UUID, offset, limit int) ([]*models.WebhookDelivery, int64, error) {
+	if _, err := s.GetWebhook(webhookID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.webhookRepo.GetDeliveriesByWebhookID(webhookID, offset, limit)
+}
+
+// validateWebhookURL accepts absolute http(s) URLs of public hosts only.
+// Deliveries check the address again when connecting, in case the host's
+// DNS records change.
+func (s *webhookService) validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ErrInvalidWebhookURL
+	}
+	if s.allowPrivateNetworks {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := webhook.CheckHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookHostNotAllowed, err)
+	}
+	return nil
+}
+
+// validateWebhookEvents accepts known event types only
+func validateWebhookEvents(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return ErrInvalidWebhookEvent
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(models.WebhookEvents, eventType) {
+			return fmt.Errorf("%w: %s", ErrInvalidWebhookEvent, eventType)
+		}
+	}
+	return nil
+}
+
+// generateWebhookSecret returns a random 32-byte hex secret
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -32,7 +32,7 @@ func NewPostgresStorage(cfg config.Config) (*PostgresStore, error) {
 
 	// Auto Migration with explicit unique constraints
 	log.Println("Running AutoMigration...")
-	err = db.AutoMigrate(&models.User{}, &models.Note{}, &models.Tag{})
+	err = db.AutoMigrate(&models.User{}, &models.Note{}, &models.Tag{}, &models.QueueTask{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.NoteVocabularyEntry{}, &models.UsageDaily{})
 	if err != nil {
 		log.Printf("AutoMigration failed: %v", err)
 		return nil, fmt.Errorf("automigration failed: %w", err)
@@ -55,6 +55,15 @@ func NewPostgresStorage(cfg config.Config) (*PostgresStore, error) {
 		}
 	}
 
+	// At most one live task per note, so concurrent enqueues can't duplicate it
+	if !db.Migrator().HasIndex(&models.QueueTask{}, "idx_queue_tasks_live_note") {
+		err = db.Exec("CREATE UNIQUE INDEX idx_queue_tasks_live_note ON queue_tasks(note_id) WHERE state <> 'dead'").Error
+		if err != nil {
+			log.Printf("Failed to create unique index on live queue tasks: %v", err)
+			return nil, err
+		}
+	}
+
 	log.Println("AutoMigration completed.")
 
 	return &PostgresStore{db: db}, nil
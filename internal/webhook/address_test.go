@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"ai-language-notes/internal/models"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // cloud metadata
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHostRejectsPrivateAddresses(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "localhost", "169.254.169.254", "::1"} {
+		if err := CheckHost(context.Background(), host); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckHost(%s) = %v, want ErrPrivateAddress", host, err)
+		}
+	}
+}
+
+func TestDeliverRefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 0)
+
+	// The receiver is on the loopback interface, as if the webhook's DNS
+	// record had been changed after it was registered
+	NewDispatcher(repo, Config{}).DeliverDue()
+
+	if hits.Load() != 0 {
+		t.Fatal("delivery reached a loopback receiver")
+	}
+	delivery := repo.delivery(id)
+	if delivery.Status != models.DeliveryPending || delivery.LastError == "" {
+		t.Fatalf("unexpected delivery state: %+v", delivery)
+	}
+}
+
+func TestDeliverDoesNotFollowRedirects(t *testing.T) {
+	var redirected atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected.Add(1)
+	}))
+	defer target.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 0)
+
+	newTestDispatcher(repo, Config{}).DeliverDue()
+
+	if redirected.Load() != 0 {
+		t.Fatal("delivery followed a redirect")
+	}
+	delivery := repo.delivery(id)
+	if delivery.Status != models.DeliveryPending || delivery.ResponseStatus != http.StatusTemporaryRedirect {
+		t.Fatalf("unexpected delivery state: %+v", delivery)
+	}
+}
@@ -0,0 +1,311 @@
+package webhook
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/repository"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// deliveryBatchSize caps how many deliveries are sent per poll
+const deliveryBatchSize = 20
+
+// Config holds the tunables of the Dispatcher
+type Config struct {
+	// PollInterval is how often due deliveries are looked for
+	PollInterval time.Duration
+	// Timeout bounds a single delivery request
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled on every subsequent attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the retry backoff
+	MaxDelay time.Duration
+	// AllowPrivateNetworks lets deliveries reach private, loopback and
+	// link-local addresses, e.g. receivers on a development machine
+	AllowPrivateNetworks bool
+}
+
+// Event is the JSON body POSTed to webhooks
+type Event struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      NoteData  `json:"data"`
+}
+
+// NoteData describes the note an event is about
+type NoteData struct {
+	NoteID           uuid.UUID               `json:"noteId"`
+	Status           models.ProcessingStatus `json:"status"`
+	OriginalText     string                  `json:"originalText"`
+	GeneratedContent string                  `json:"generatedContent,omitempty"`
+	Analysis         *models.NoteAnalysis    `json:"analysis,omitempty"`
+	Tags             []string                `json:"tags,omitempty"`
+	ErrorMessage     string                  `json:"errorMessage,omitempty"`
+	Attempts         int                     `json:"attempts"`
+}
+
+// Dispatcher records webhook deliveries for note events and sends them,
+// retrying failed deliveries with exponential backoff
+type Dispatcher struct {
+	webhookRepo repository.WebhookRepository
+	httpClient  *http.Client
+	config      Config
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(webhookRepo repository.WebhookRepository, config Config) *Dispatcher {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 8
+	}
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = 30 * time.Second
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = time.Hour
+	}
+	if config.MaxDelay < config.BaseDelay {
+		config.MaxDelay = config.BaseDelay
+	}
+
+	return &Dispatcher{
+		webhookRepo: webhookRepo,
+		httpClient:  newHTTPClient(config.Timeout, config.AllowPrivateNetworks),
+		config:      config,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// NotifyNote queues deliveries to the owner's webhooks if the note reached a
+// final status. Other statuses are ignored.
+func (d *Dispatcher) NotifyNote(note *models.Note) error {
+	var eventType string
+	switch note.Status {
+	case models.StatusCompleted:
+		eventType = models.WebhookEventNoteCompleted
+	case models.StatusFailed:
+		eventType = models.WebhookEventNoteFailed
+	default:
+		return nil
+	}
+
+	webhooks, err := d.webhookRepo.GetActiveWebhooksForEvent(note.UserID, eventType)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	tags := make([]string, len(note.Tags))
+	for i, tag := range note.Tags {
+		tags[i] = tag.Name
+	}
+
+	for _, webhook := range webhooks {
+		payload, err := json.Marshal(Event{
+			ID:        uuid.New(),
+			Type:      eventType,
+			CreatedAt: time.Now(),
+			Data: NoteData{
+				NoteID:           note.ID,
+				Status:           note.Status,
+				OriginalText:     note.OriginalText,
+				GeneratedContent: note.GeneratedContent,
+				Analysis:         note.Analysis,
+				Tags:             tags,
+				ErrorMessage:     note.ErrorMessage,
+				Attempts:         note.Attempts,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook event: %w", err)
+		}
+
+		err = d.webhookRepo.CreateDelivery(&models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start begins sending due deliveries
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.loop()
+	log.Println("Started webhook dispatcher")
+}
+
+// Stop stops sending deliveries. Deliveries in progress are finished.
+func (d *Dispatcher) Stop() {
+	close(d.stopCh)
+	d.wg.Wait()
+	log.Println("Webhook dispatcher stopped")
+}
+
+// loop polls for due deliveries until stopped
+func (d *Dispatcher) loop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.DeliverDue()
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many were sent
+func (d *Dispatcher) DeliverDue() int {
+	// Lease the batch long enough to send every delivery in it
+	lease := d.config.Timeout*deliveryBatchSize + time.Minute
+	deliveries, err := d.webhookRepo.ClaimDueDeliveries(deliveryBatchSize, lease)
+	if err != nil {
+		log.Printf("Failed to load due webhook deliveries: %v", err)
+		return 0
+	}
+
+	webhooks := make(map[uuid.UUID]*models.Webhook)
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = d.webhookRepo.GetWebhookByID(delivery.WebhookID)
+			if errors.Is(err, repository.ErrWebhookNotFound) {
+				continue // Deleted along with its deliveries
+			}
+			if err != nil {
+				log.Printf("Failed to load webhook %s: %v", delivery.WebhookID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		d.deliver(webhook, delivery)
+	}
+
+	return len(deliveries)
+}
+
+// deliver sends a single delivery and records the outcome
+func (d *Dispatcher) deliver(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+
+	status, err := d.send(webhook, delivery)
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		log.Printf("Webhook delivery %s failed permanently after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(d.retryDelay(delivery.Attempts))
+	}
+
+	if err := d.webhookRepo.UpdateDelivery(delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs the signed payload and returns the response status
+func (d *Dispatcher) send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.config.Timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ai-language-notes-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay returns the exponential backoff before the given attempt is retried
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.config.MaxDelay {
+			return d.config.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>" with the
+// webhook's secret. Receivers recompute it to verify a delivery and should
+// reject stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,373 @@
+package webhook
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/repository"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeWebhookRepo keeps webhooks and deliveries in memory. ClaimDueDeliveries
+// leases deliveries like the Postgres repository does, by pushing their next
+// attempt past the lease.
+type fakeWebhookRepo struct {
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries map[uuid.UUID]*models.WebhookDelivery
+	leases     []time.Duration
+}
+
+func newFakeWebhookRepo() *fakeWebhookRepo {
+	return &fakeWebhookRepo{
+		webhooks:   make(map[uuid.UUID]*models.Webhook),
+		deliveries: make(map[uuid.UUID]*models.WebhookDelivery),
+	}
+}
+
+func (r *fakeWebhookRepo) CreateWebhook(webhook *models.Webhook) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.New()
+	}
+	r.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (r *fakeWebhookRepo) GetWebhookByID(id uuid.UUID) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, repository.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (r *fakeWebhookRepo) GetWebhooksByUserID(userID uuid.UUID) ([]*models.Webhook, error) {
+	return r.GetActiveWebhooksForEvent(userID, "")
+}
+
+func (r *fakeWebhookRepo) GetActiveWebhooksForEvent(userID uuid.UUID, eventType string) ([]*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var webhooks []*models.Webhook
+	for _, webhook := range r.webhooks {
+		if webhook.UserID == userID && webhook.Active {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *fakeWebhookRepo) UpdateWebhook(webhook *models.Webhook) (*models.Webhook, error) {
+	return r.CreateWebhook(webhook)
+}
+
+func (r *fakeWebhookRepo) DeleteWebhook(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *fakeWebhookRepo) CreateDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.New()
+	}
+	copied := *delivery
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueDeliveries(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leases = append(r.leases, lease)
+
+	now := time.Now()
+	var claimed []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.NextAttemptAt = now.Add(lease)
+		copied := *delivery
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *delivery
+	r.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (r *fakeWebhookRepo) GetDeliveriesByWebhookID(webhookID uuid.UUID, offset, limit int) ([]*models.WebhookDelivery, int64, error) {
+	return nil, 0, nil
+}
+
+// delivery returns the stored state of a delivery
+func (r *fakeWebhookRepo) delivery(id uuid.UUID) models.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.deliveries[id]
+}
+
+// addDelivery stores a due delivery to the given webhook
+func (r *fakeWebhookRepo) addDelivery(t *testing.T, webhook *models.Webhook, attempts int) uuid.UUID {
+	t.Helper()
+	delivery := &models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhook.ID,
+		EventType:     models.WebhookEventNoteCompleted,
+		Payload:       `{"type":"note.completed"}`,
+		Status:        models.DeliveryPending,
+		Attempts:      attempts,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	}
+	if err := r.CreateDelivery(delivery); err != nil {
+		t.Fatalf("CreateDelivery: %v", err)
+	}
+	return delivery.ID
+}
+
+// newTestDispatcher creates a dispatcher that may deliver to the test
+// receivers on the loopback interface
+func newTestDispatcher(repo *fakeWebhookRepo, config Config) *Dispatcher {
+	config.AllowPrivateNetworks = true
+	return NewDispatcher(repo, config)
+}
+
+func newTestWebhook(t *testing.T, repo *fakeWebhookRepo, url string) *models.Webhook {
+	t.Helper()
+	webhook, err := repo.CreateWebhook(&models.Webhook{
+		UserID: uuid.New(),
+		URL:    url,
+		Secret: "s3cr3t",
+		Events: []string{models.WebhookEventNoteCompleted},
+		Active: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return webhook
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", "1700000000", body); got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+	if Sign("other", "1700000000", body) == want {
+		t.Fatal("signatures with different secrets must differ")
+	}
+	if Sign("secret", "1700000001", body) == want {
+		t.Fatal("signatures with different timestamps must differ")
+	}
+}
+
+func TestDeliverSignsRequest(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   string
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 0)
+
+	if n := newTestDispatcher(repo, Config{}).DeliverDue(); n != 1 {
+		t.Fatalf("DeliverDue = %d, want 1", n)
+	}
+
+	req := <-requests
+	if req.body != `{"type":"note.completed"}` {
+		t.Fatalf("unexpected body %q", req.body)
+	}
+	if got := req.header.Get(HeaderEvent); got != models.WebhookEventNoteCompleted {
+		t.Errorf("%s = %q", HeaderEvent, got)
+	}
+	if got := req.header.Get(HeaderDelivery); got != id.String() {
+		t.Errorf("%s = %q, want %s", HeaderDelivery, got, id)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	timestamp := req.header.Get(HeaderTimestamp)
+	want := "sha256=" + Sign(webhook.Secret, timestamp, []byte(req.body))
+	if got := req.header.Get(HeaderSignature); got != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+	}
+
+	delivery := repo.delivery(id)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusNoContent {
+		t.Fatalf("unexpected delivery state: %+v", delivery)
+	}
+	if delivery.DeliveredAt == nil {
+		t.Fatal("expected DeliveredAt to be set")
+	}
+}
+
+func TestDeliverRetriesWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 2)
+
+	config := Config{BaseDelay: time.Minute, MaxDelay: time.Hour, MaxAttempts: 5}
+	before := time.Now()
+	newTestDispatcher(repo, config).DeliverDue()
+
+	delivery := repo.delivery(id)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 3 {
+		t.Fatalf("got status %s after %d attempts, want pending after 3", delivery.Status, delivery.Attempts)
+	}
+	if delivery.ResponseStatus != http.StatusServiceUnavailable || !strings.Contains(delivery.LastError, "503") {
+		t.Fatalf("unexpected outcome: status %d, error %q", delivery.ResponseStatus, delivery.LastError)
+	}
+
+	// Third attempt failed, the fourth waits 4x the base delay
+	wait := delivery.NextAttemptAt.Sub(before)
+	if wait < 4*time.Minute || wait > 4*time.Minute+5*time.Second {
+		t.Fatalf("next attempt in %s, want 4m", wait)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	d := NewDispatcher(newFakeWebhookRepo(), Config{BaseDelay: 30 * time.Second, MaxDelay: time.Hour})
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := d.retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliverFailsPermanentlyAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 2)
+
+	dispatcher := newTestDispatcher(repo, Config{MaxAttempts: 3})
+	dispatcher.DeliverDue()
+
+	delivery := repo.delivery(id)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 3 {
+		t.Fatalf("got status %s after %d attempts, want failed after 3", delivery.Status, delivery.Attempts)
+	}
+	if delivery.LastError == "" {
+		t.Fatal("expected the last error to be recorded")
+	}
+
+	// Failed deliveries are never claimed again
+	if n := dispatcher.DeliverDue(); n != 0 {
+		t.Fatalf("DeliverDue after permanent failure = %d, want 0", n)
+	}
+}
+
+func TestDeliverDueLeasesClaimedDeliveries(t *testing.T) {
+	release := make(chan struct{})
+	var calls sync.WaitGroup
+	calls.Add(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Done()
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, server.URL)
+	id := repo.addDelivery(t, webhook, 0)
+
+	config := Config{Timeout: 5 * time.Second}
+	dispatcher := newTestDispatcher(repo, config)
+
+	done := make(chan int)
+	go func() { done <- dispatcher.DeliverDue() }()
+	calls.Wait()
+
+	// While the first send is in progress another poll must not resend it
+	if n := newTestDispatcher(repo, config).DeliverDue(); n != 0 {
+		t.Fatalf("concurrent DeliverDue = %d, want 0", n)
+	}
+	close(release)
+	if n := <-done; n != 1 {
+		t.Fatalf("DeliverDue = %d, want 1", n)
+	}
+
+	// The lease covers sending a full batch
+	for _, lease := range repo.leases {
+		if lease < config.Timeout*deliveryBatchSize {
+			t.Fatalf("lease %s is shorter than a batch of %d sends", lease, deliveryBatchSize)
+		}
+	}
+	if got := repo.delivery(id).Status; got != models.DeliverySucceeded {
+		t.Fatalf("status = %s, want succeeded", got)
+	}
+}
+
+func TestDeliverDueSkipsDeletedWebhooks(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	webhook := newTestWebhook(t, repo, "http://127.0.0.1:1")
+	id := repo.addDelivery(t, webhook, 0)
+	repo.DeleteWebhook(webhook.ID)
+
+	newTestDispatcher(repo, Config{}).DeliverDue()
+
+	if delivery := repo.delivery(id); delivery.Attempts != 0 {
+		t.Fatalf("delivery of a deleted webhook was attempted %d times", delivery.Attempts)
+	}
+}
@@ -0,0 +1,199 @@
+package worker
+
+import (
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/repository"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// reconcileBatchSize caps how many stale notes are recovered per run
+const reconcileBatchSize = 500
+
+// ReconcilerConfig holds the tunables of the Reconciler
+type ReconcilerConfig struct {
+	// Interval is how often the reconciler looks for stuck notes
+	Interval time.Duration
+	// StaleAfter is how long a note may sit in pending or processing before
+	// it is considered lost. It must exceed the queue's visibility timeout.
+	StaleAfter time.Duration
+}
+
+// ReconcilerMetrics holds Prometheus metrics for the reconciler
+type ReconcilerMetrics struct {
+	Recovered *prometheus.CounterVec
+	Failures  prometheus.Counter
+}
+
+// NewReconcilerMetrics creates and registers metrics for the reconciler
+func NewReconcilerMetrics() *ReconcilerMetrics {
+	recovered := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "note_reconciler_recovered_total",
+			Help: "Total number of stuck notes re-enqueued by the reconciler",
+		},
+		[]string{"status"},
+	)
+
+	failures := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "note_reconciler_failures_total",
+			Help: "Total number of stuck notes the reconciler failed to re-enqueue",
+		},
+	)
+
+	// Register metrics with Prometheus
+	prometheus.MustRegister(recovered, failures)
+
+	return &ReconcilerMetrics{
+		Recovered: recovered,
+		Failures:  failures,
+	}
+}
+
+// Reconciler re-enqueues notes that are stuck in pending or processing,
+// e.g. because Redis was flushed or a process died mid-task
+type Reconciler struct {
+	queueService queue.Queue
+	noteRepo     repository.NoteRepository
+	userRepo     repository.UserRepository
+	config       ReconcilerConfig
+	metrics      *ReconcilerMetrics
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(
+	queueService queue.Queue,
+	noteRepo repository.NoteRepository,
+	userRepo repository.UserRepository,
+	config ReconcilerConfig,
+) *Reconciler {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Minute
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 15 * time.Minute
+	}
+
+	return &Reconciler{
+		queueService: queueService,
+		noteRepo:     noteRepo,
+		userRepo:     userRepo,
+		config:       config,
+		metrics:      NewReconcilerMetrics(),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start runs a reconciliation immediately and then periodically
+func (r *Reconciler) Start() {
+	r.wg.Add(1)
+	go r.loop()
+	log.Printf("Started note reconciler (interval %s, stale after %s)", r.config.Interval, r.config.StaleAfter)
+}
+
+// Stop stops the periodic reconciliation
+func (r *Reconciler) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+	log.Println("Note reconciler stopped")
+}
+
+// loop runs reconciliations until stopped
+func (r *Reconciler) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		r.Reconcile(ctx)
+		cancel()
+
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile re-enqueues stale notes and returns how many were recovered
+func (r *Reconciler) Reconcile(ctx context.Context) int {
+	notes, err := r.noteRepo.GetStaleNotes(
+		[]models.ProcessingStatus{models.StatusPending, models.StatusProcessing},
+		time.Now().Add(-r.config.StaleAfter),
+		reconcileBatchSize,
+	)
+	if err != nil {
+		log.Printf("Reconciler failed to load stale notes: %v", err)
+		return 0
+	}
+
+	users := make(map[uuid.UUID]*models.User)
+	recovered := 0
+
+	for _, note := range notes {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+
+		user, ok := users[note.UserID]
+		if !ok {
+			user, err = r.userRepo.GetUserByID(note.UserID)
+			if err != nil {
+				log.Printf("Reconciler failed to load owner of note %s: %v", note.ID, err)
+				r.metrics.Failures.Inc()
+				continue
+			}
+			users[note.UserID] = user
+		}
+
+		// A note left processing may still be marked as claimed when the
+		// task arrives, so let the worker take it over
+		task := &queue.LLMProcessingTask{
+			NoteID:         note.ID,
+			OriginalText:   note.OriginalText,
+			UserID:         note.UserID,
+			NativeLanguage: user.NativeLanguage,
+			TargetLanguage: user.TargetLanguage,
+			CreatedAt:      time.Now(),
+			Priority:       queue.PriorityReprocess,
+			Redelivered:    note.Status == models.StatusProcessing,
+		}
+		added, err := r.queueService.EnqueueTask(ctx, task)
+		if err != nil {
+			log.Printf("Reconciler failed to re-enqueue note %s: %v", note.ID, err)
+			r.metrics.Failures.Inc()
+			continue
+		}
+		if !added {
+			// The note still has a live task, e.g. one waiting for its retry
+			continue
+		}
+
+		// Reset the note so it reads as freshly queued and isn't picked up
+		// again until it goes stale once more. A worker that already
+		// claimed it has moved it on.
+		if _, err := r.noteRepo.ResetStaleNote(note); err != nil {
+			log.Printf("Reconciler failed to reset note %s: %v", note.ID, err)
+		}
+
+		r.metrics.Recovered.WithLabelValues(string(note.Status)).Inc()
+		recovered++
+	}
+
+	if recovered > 0 {
+		log.Printf("Reconciler re-enqueued %d stuck notes", recovered)
+	}
+	return recovered
+}
@@ -2,57 +2,191 @@ package worker
 
 import (
 	"ai-language-notes/internal/ai"
+	"ai-language-notes/internal/events"
 	"ai-language-notes/internal/models"
 	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/quota"
 	"ai-language-notes/internal/repository"
+	"ai-language-notes/internal/webhook"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+// Config holds the tunables of the worker pool
+type Config struct {
+	// WorkerCount is the number of concurrent processing goroutines
+	WorkerCount int
+	// HeartbeatInterval is how often the worker renews its queue leases.
+	// It must be shorter than the queue's visibility timeout.
+	HeartbeatInterval time.Duration
+	// ReaperInterval is how often in-flight tasks of dead workers are re-queued
+	ReaperInterval time.Duration
+	// MaxAttempts is how many times a task is attempted before the note is
+	// marked failed and the task is dead-lettered
+	MaxAttempts int
+	// RetryBaseDelay is the delay before the first retry, doubled on every
+	// subsequent attempt
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay caps the exponential retry backoff
+	RetryMaxDelay time.Duration
+	// DrainTimeout is how long Stop lets in-flight tasks finish before they
+	// are cancelled and handed back to the queue
+	DrainTimeout time.Duration
+}
+
+// retryPollInterval is how often delayed retries are checked for due tasks
+const retryPollInterval = time.Second
+
+// errNoteDeleted is the cancellation cause of tasks whose note was deleted
+var errNoteDeleted = errors.New("note was deleted")
+
+// permanentError marks a failure that retrying the task won't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
 // Worker is responsible for processing LLM tasks
 type Worker struct {
-	queueService *queue.QueueService
+	queueService queue.Queue
 	noteRepo     repository.NoteRepository
 	userRepo     repository.UserRepository
+	usageRepo    repository.UsageRepository
+	quotas       quota.Limiter // nil disables quotas
 	llmService   ai.LLMService
-	workerCount  int
+	bus          events.Bus
+	webhooks     *webhook.Dispatcher
+	config       Config
+	instanceID   string
 	stopCh       chan struct{}
+	drainedCh    chan struct{} // closed once no task is in flight anymore
 	wg           sync.WaitGroup
+	bgWg         sync.WaitGroup
+
+	// dequeueCtx is cancelled as soon as Stop is called, taskCtx once the
+	// drain deadline has passed
+	dequeueCtx  context.Context
+	stopDequeue context.CancelFunc
+	taskCtx     context.Context
+	cancelTasks context.CancelFunc
+
+	// running maps notes being processed to the cancel func of their LLM call
+	runningMu sync.Mutex
+	running   map[uuid.UUID]context.CancelCauseFunc
 }
 
 // NewWorker creates a new worker
 func NewWorker(
-	queueService *queue.QueueService,
+	queueService queue.Queue,
 	noteRepo repository.NoteRepository,
 	userRepo repository.UserRepository,
+	usageRepo repository.UsageRepository,
+	quotas quota.Limiter,
 	llmService ai.LLMService,
-	workerCount int,
+	bus events.Bus,
+	webhooks *webhook.Dispatcher,
+	config Config,
 ) *Worker {
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = queue.DefaultVisibilityTimeout / 3
+	}
+	if config.ReaperInterval <= 0 {
+		config.ReaperInterval = time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = 30 * time.Second
+	}
+	if config.RetryMaxDelay < config.RetryBaseDelay {
+		config.RetryMaxDelay = config.RetryBaseDelay
+	}
+	if config.DrainTimeout <= 0 {
+		config.DrainTimeout = 30 * time.Second
+	}
+
+	dequeueCtx, stopDequeue := context.WithCancel(context.Background())
+	taskCtx, cancelTasks := context.WithCancel(context.Background())
+
 	return &Worker{
 		queueService: queueService,
 		noteRepo:     noteRepo,
 		userRepo:     userRepo,
+		usageRepo:    usageRepo,
+		quotas:       quotas,
 		llmService:   llmService,
-		workerCount:  workerCount,
+		bus:          bus,
+		webhooks:     webhooks,
+		config:       config,
+		instanceID:   newInstanceID(),
 		stopCh:       make(chan struct{}),
+		drainedCh:    make(chan struct{}),
+		dequeueCtx:   dequeueCtx,
+		stopDequeue:  stopDequeue,
+		taskCtx:      taskCtx,
+		cancelTasks:  cancelTasks,
+		running:      make(map[uuid.UUID]context.CancelCauseFunc),
 	}
 }
 
 // Start begins the worker processing
 func (w *Worker) Start() {
-	for i := 0; i < w.workerCount; i++ {
+	for i := 0; i < w.config.WorkerCount; i++ {
 		w.wg.Add(1)
 		go w.processLoop(i)
 	}
-	log.Printf("Started %d workers for LLM processing", w.workerCount)
+
+	w.bgWg.Add(3)
+	go w.heartbeatLoop()
+	go w.reaperLoop()
+	go w.retryLoop()
+
+	sub, err := w.bus.Subscribe(context.Background(), events.NoteCancelTopic)
+	if err != nil {
+		log.Printf("Failed to subscribe to note cancellations, deleted notes will finish processing: %v", err)
+	} else {
+		w.bgWg.Add(1)
+		go w.cancelLoop(sub)
+	}
+
+	log.Printf("Started %d workers for LLM processing (instance %s)", w.config.WorkerCount, w.instanceID)
 }
 
-// Stop gracefully stops the worker
+// Stop gracefully stops the worker. Dequeuing stops immediately; in-flight
+// tasks get until the drain timeout to finish, after which they are
+// cancelled and handed back to the queue.
 func (w *Worker) Stop() {
 	close(w.stopCh)
-	w.wg.Wait()
+	w.stopDequeue()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(w.config.DrainTimeout):
+		log.Printf("Drain timeout of %s reached, cancelling in-flight tasks", w.config.DrainTimeout)
+		w.cancelTasks()
+		<-done
+	}
+
+	// Leases must be kept alive until the last task is settled
+	close(w.drainedCh)
+	w.bgWg.Wait()
+	w.cancelTasks()
 	log.Println("All workers stopped")
 }
 
@@ -60,6 +194,7 @@ func (w *Worker) Stop() {
 func (w *Worker) processLoop(workerID int) {
 	defer w.wg.Done()
 
+	consumerID := w.consumerID(workerID)
 	log.Printf("Worker %d started", workerID)
 
 	for {
@@ -69,46 +204,354 @@ func (w *Worker) processLoop(workerID int) {
 			return
 		default:
 			// Create a timeout context for task processing
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			ctx, cancel := context.WithTimeout(w.dequeueCtx, 5*time.Minute)
 
 			// Try to get a task from the queue
-			task, err := w.queueService.DequeueTask(ctx)
+			task, err := w.queueService.DequeueTask(ctx, consumerID)
 			cancel()
 
 			if err != nil {
+				if w.dequeueCtx.Err() != nil {
+					log.Printf("Worker %d received stop signal", workerID)
+					return
+				}
 				log.Printf("Worker %d error dequeueing task: %v", workerID, err)
 				// Small pause to prevent tight loop if there's a persistent error
 				time.Sleep(1 * time.Second)
 				continue
 			}
 
-			w.processTask(task, workerID)
+			err = w.processTask(task, workerID)
+			w.settleTask(task, workerID, err)
 		}
 	}
 }
 
-// processTask processes a single LLM task
-func (w *Worker) processTask(task *queue.LLMProcessingTask, workerID int) {
-	log.Printf("Worker %d processing note %s", workerID, task.NoteID)
+// settleTask acknowledges, re-queues or dead-letters a task depending on
+// the outcome of processing it
+func (w *Worker) settleTask(task *queue.LLMProcessingTask, workerID int, processErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Get the note from the database
-	note, err := w.noteRepo.GetNoteByID(task.NoteID)
+	consumerID := w.consumerID(workerID)
+
+	if processErr == nil {
+		if err := w.queueService.AckTask(ctx, consumerID, task); err != nil {
+			log.Printf("Worker %d failed to ack note %s: %v", workerID, task.NoteID, err)
+		}
+		return
+	}
+
+	// Cancelled by shutdown, the attempt doesn't count
+	if w.taskCtx.Err() != nil {
+		log.Printf("Worker %d handing back unfinished note %s", workerID, task.NoteID)
+		if err := w.queueService.ReleaseTask(ctx, consumerID, task); err != nil {
+			log.Printf("Worker %d failed to release note %s: %v", workerID, task.NoteID, err)
+		}
+		w.markNotePending(task.NoteID, workerID)
+		return
+	}
+
+	var permanent *permanentError
+	isPermanent := errors.As(processErr, &permanent)
+
+	if !isPermanent && task.Attempts+1 < w.config.MaxAttempts {
+		attempts := task.Attempts + 1
+		dueAt := time.Now().Add(w.retryDelay(attempts))
+
+		log.Printf("Worker %d retrying note %s at %s (attempt %d/%d): %v",
+			workerID, task.NoteID, dueAt.Format(time.RFC3339), attempts, w.config.MaxAttempts, processErr)
+		err := w.queueService.ScheduleRetry(ctx, consumerID, task, dueAt)
+		if errors.Is(err, queue.ErrLeaseLost) {
+			// The reaper already handed the task to another consumer
+			log.Printf("Worker %d lost the lease on note %s, leaving it to its new consumer", workerID, task.NoteID)
+			return
+		}
+		if err != nil {
+			log.Printf("Worker %d failed to schedule retry for note %s: %v", workerID, task.NoteID, err)
+			// Fall back to an immediate retry rather than leaving the task in flight
+			if err := w.queueService.NackTask(ctx, consumerID, task); err != nil {
+				log.Printf("Worker %d failed to requeue note %s: %v", workerID, task.NoteID, err)
+			}
+			return
+		}
+		w.markNoteRetrying(task.NoteID, workerID, attempts, dueAt, processErr)
+		return
+	}
+
+	log.Printf("Worker %d dead-lettering note %s after %d attempts: %v", workerID, task.NoteID, task.Attempts+1, processErr)
+	err := w.queueService.DeadLetterTask(ctx, consumerID, task, processErr)
+	if errors.Is(err, queue.ErrLeaseLost) {
+		log.Printf("Worker %d lost the lease on note %s, leaving it to its new consumer", workerID, task.NoteID)
+		return
+	}
+	if err != nil {
+		log.Printf("Worker %d failed to dead-letter note %s: %v", workerID, task.NoteID, err)
+	}
+
+	// Permanent failures were already recorded on the note
+	if !isPermanent {
+		w.markNoteFailed(task.NoteID, workerID, task.Attempts+1, processErr)
+	}
+}
+
+// retryDelay returns the exponential backoff before the given attempt is retried
+func (w *Worker) retryDelay(attempts int) time.Duration {
+	delay := w.config.RetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= w.config.RetryMaxDelay {
+			return w.config.RetryMaxDelay
+		}
+	}
+	return delay
+}
+
+// markNoteRetrying records a scheduled retry on the note. If that fails the
+// claim is still released, so the retry isn't skipped as already claimed.
+func (w *Worker) markNoteRetrying(noteID uuid.UUID, workerID int, attempts int, dueAt time.Time, cause error) {
+	note, err := w.noteRepo.GetNoteByID(noteID)
+	if errors.Is(err, repository.ErrNoteNotFound) {
+		return
+	}
 	if err != nil {
-		log.Printf("Worker %d failed to get note %s: %v", workerID, task.NoteID, err)
+		log.Printf("Worker %d failed to load note %s to record its retry: %v", workerID, noteID, err)
+		w.releaseNote(noteID, workerID)
 		return
 	}
 
-	// Update note status to processing
-	note.Status = models.StatusProcessing
-	_, err = w.noteRepo.UpdateNote(note)
+	note.Status = models.StatusPending
+	note.ErrorMessage = "Failed to process text: " + cause.Error()
+	note.Attempts = attempts
+	note.NextRetryAt = &dueAt
+	if _, err := w.noteRepo.UpdateNote(note); err != nil {
+		log.Printf("Worker %d failed to update note with retry status: %v", workerID, err)
+		w.releaseNote(noteID, workerID)
+		return
+	}
+	w.publishStatus(note)
+}
+
+// releaseNote hands the claim on a note back so its next task can pick it up
+func (w *Worker) releaseNote(noteID uuid.UUID, workerID int) {
+	if err := w.noteRepo.ReleaseNote(noteID); err != nil {
+		log.Printf("Worker %d failed to release note %s: %v", workerID, noteID, err)
+	}
+}
+
+// markNotePending puts a note that was being processed back to pending
+func (w *Worker) markNotePending(noteID uuid.UUID, workerID int) {
+	note, err := w.noteRepo.GetNoteByID(noteID)
+	if err != nil || note.Status != models.StatusProcessing {
+		return
+	}
+
+	note.Status = models.StatusPending
+	if _, err := w.noteRepo.UpdateNote(note); err != nil {
+		log.Printf("Worker %d failed to reset note status: %v", workerID, err)
+		return
+	}
+	w.publishStatus(note)
+}
+
+// markNoteFailed makes a best-effort attempt to flag a note as failed
+func (w *Worker) markNoteFailed(noteID uuid.UUID, workerID int, attempts int, cause error) {
+	note, err := w.noteRepo.GetNoteByID(noteID)
 	if err != nil {
-		log.Printf("Worker %d failed to update note status to processing: %v", workerID, err)
 		return
 	}
 
+	note.Status = models.StatusFailed
+	note.ErrorMessage = "Failed to process text: " + cause.Error()
+	note.Attempts = attempts
+	note.NextRetryAt = nil
+	if _, err := w.noteRepo.UpdateNote(note); err != nil {
+		log.Printf("Worker %d failed to update note with error status: %v", workerID, err)
+		return
+	}
+	w.publishStatus(note)
+}
+
+// publishStatus announces a note's current status to its owner's live
+// connections. Delivery is best effort, clients can always fall back to polling.
+// Final statuses are also queued for the owner's webhooks.
+func (w *Worker) publishStatus(note *models.Note) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := events.PublishNoteEvent(ctx, w.bus, note); err != nil {
+		log.Printf("Failed to publish status of note %s: %v", note.ID, err)
+	}
+
+	if w.webhooks != nil {
+		if err := w.webhooks.NotifyNote(note); err != nil {
+			log.Printf("Failed to queue webhooks for note %s: %v", note.ID, err)
+		}
+	}
+}
+
+// heartbeatLoop keeps the queue leases of all local consumers alive until
+// the last in-flight task is settled
+func (w *Worker) heartbeatLoop() {
+	defer w.bgWg.Done()
+
+	ticker := time.NewTicker(w.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		for i := 0; i < w.config.WorkerCount; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := w.queueService.Heartbeat(ctx, w.consumerID(i)); err != nil {
+				log.Printf("Worker %d heartbeat failed: %v", i, err)
+			}
+			cancel()
+		}
+
+		select {
+		case <-w.drainedCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reaperLoop periodically re-queues tasks held by workers that died
+func (w *Worker) reaperLoop() {
+	defer w.bgWg.Done()
+
+	ticker := time.NewTicker(w.config.ReaperInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			requeued, err := w.queueService.RequeueOrphanedTasks(ctx)
+			cancel()
+
+			if err != nil {
+				log.Printf("Reaper failed to requeue orphaned tasks: %v", err)
+			}
+			if requeued > 0 {
+				log.Printf("Reaper re-queued %d orphaned tasks", requeued)
+			}
+		}
+	}
+}
+
+// cancelLoop aborts the LLM call of notes that get deleted while being processed
+func (w *Worker) cancelLoop(sub events.Subscription) {
+	defer w.bgWg.Done()
+	defer sub.Close()
+
+	for {
+		select {
+		case <-w.drainedCh:
+			return
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+			noteID, err := uuid.ParseBytes(msg.Payload)
+			if err != nil {
+				continue
+			}
+
+			w.runningMu.Lock()
+			cancel, ok := w.running[noteID]
+			w.runningMu.Unlock()
+			if ok {
+				log.Printf("Cancelling processing of deleted note %s", noteID)
+				cancel(errNoteDeleted)
+			}
+		}
+	}
+}
+
+// track registers a note as being processed and returns a context that is
+// cancelled if the note is deleted, and a func to unregister it
+func (w *Worker) track(noteID uuid.UUID) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(w.taskCtx)
+
+	w.runningMu.Lock()
+	w.running[noteID] = cancel
+	w.runningMu.Unlock()
+
+	return ctx, func() {
+		w.runningMu.Lock()
+		delete(w.running, noteID)
+		w.runningMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// retryLoop moves delayed retries onto the processing queue once they are due
+func (w *Worker) retryLoop() {
+	defer w.bgWg.Done()
+
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if _, err := w.queueService.PromoteDueTasks(ctx); err != nil {
+				log.Printf("Failed to promote delayed retries: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// processTask processes a single LLM task. A returned error means the task
+// should be retried with backoff unless it is a permanentError, in which
+// case the failure has been recorded on the note and the task is dead-lettered.
+func (w *Worker) processTask(task *queue.LLMProcessingTask, workerID int) error {
+	log.Printf("Worker %d processing note %s", workerID, task.NoteID)
+
+	// Claim the note so duplicate tasks for it become no-ops. A redelivered
+	// task may find the note still marked processing by its previous consumer.
+	claimable := []models.ProcessingStatus{models.StatusPending}
+	if task.Redelivered {
+		claimable = append(claimable, models.StatusProcessing)
+	}
+	claimed, err := w.noteRepo.ClaimNote(task.NoteID, claimable)
+	if err != nil {
+		return fmt.Errorf("failed to claim note: %w", err)
+	}
+	if !claimed {
+		log.Printf("Worker %d skipping note %s: already claimed, processed or deleted", workerID, task.NoteID)
+		return nil
+	}
+
+	// Listen for deletion before loading the note so it can't slip through
+	noteCtx, untrack := w.track(task.NoteID)
+	defer untrack()
+
+	// Get the note from the database
+	note, err := w.noteRepo.GetNoteByID(task.NoteID)
+	if errors.Is(err, repository.ErrNoteNotFound) {
+		log.Printf("Worker %d skipping note %s: deleted", workerID, task.NoteID)
+		return nil
+	}
+	if err != nil {
+		// Hand the claim back so the retry can pick the note up again
+		w.releaseNote(task.NoteID, workerID)
+		return fmt.Errorf("failed to get note: %w", err)
+	}
+	w.publishStatus(note)
+
 	// Process the text with LLM service
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	ctx, cancel := context.WithTimeout(noteCtx, 3*time.Minute)
 	defer cancel()
+	if task.BypassCache {
+		ctx = ai.WithoutCache(ctx)
+	}
 
 	processedContent, err := w.llmService.ProcessText(
 		ctx,
@@ -117,20 +560,51 @@ func (w *Worker) processTask(task *queue.LLMProcessingTask, workerID int) {
 		task.TargetLanguage,
 	)
 
+	// The tokens are spent whatever becomes of the attempt: retried,
+	// dead-lettered, dropped with its deleted note or saved
+	usage := ai.UsageOf(err)
+	if err == nil {
+		usage = processedContent.AllUsage()
+	}
+	w.recordUsage(task.UserID, usage)
+
+	if errors.Is(context.Cause(noteCtx), errNoteDeleted) {
+		log.Printf("Worker %d dropped note %s: deleted during processing", workerID, task.NoteID)
+		return nil
+	}
+
 	if err != nil {
 		log.Printf("Worker %d LLM processing failed for note %s: %v", workerID, task.NoteID, err)
+
+		// Requests the provider rejected outright won't succeed later either
+		var llmErr *ai.LLMError
+		if !errors.As(err, &llmErr) || llmErr.Retryable {
+			return fmt.Errorf("LLM processing failed: %w", err)
+		}
+
 		note.Status = models.StatusFailed
 		note.ErrorMessage = "Failed to process text: " + err.Error()
+		note.Attempts = task.Attempts + 1
+		note.NextRetryAt = nil
 		_, updateErr := w.noteRepo.UpdateNote(note)
 		if updateErr != nil {
 			log.Printf("Worker %d failed to update note with error status: %v", workerID, updateErr)
+		} else {
+			w.publishStatus(note)
 		}
-		return
+		return &permanentError{err: err}
 	}
 
 	// Update note with processed content
 	note.GeneratedContent = processedContent.Content
+	note.Provider = processedContent.Provider
+	note.PromptVersion = processedContent.PromptVersion
+	note.Analysis = processedContent.Analysis
+	note.Usage = processedContent.Usage
 	note.Status = models.StatusCompleted
+	note.ErrorMessage = ""
+	note.Attempts = task.Attempts + 1
+	note.NextRetryAt = nil
 
 	// Add tags from processed content
 	var tags []models.Tag
@@ -143,10 +617,55 @@ func (w *Worker) processTask(task *queue.LLMProcessingTask, workerID int) {
 
 	// Save the updated note
 	_, err = w.noteRepo.UpdateNote(note)
+	if errors.Is(err, repository.ErrNoteNotFound) {
+		log.Printf("Worker %d dropped note %s: deleted during processing", workerID, task.NoteID)
+		return nil
+	}
 	if err != nil {
-		log.Printf("Worker %d failed to save processed note: %v", workerID, err)
-		return
+		return fmt.Errorf("failed to save processed note: %w", err)
 	}
+	w.publishStatus(note)
+
+	log.Printf("Worker %d successfully processed note %s with %s", workerID, task.NoteID, note.Provider)
+	return nil
+}
 
-	log.Printf("Worker %d successfully processed note %s", workerID, task.NoteID)
+// recordUsage adds the LLM usage of an attempt to the user's daily totals,
+// under each model's own row, and to their token quota. Cached responses
+// have no usage and aren't counted.
+// Failures are only logged.
+func (w *Worker) recordUsage(userID uuid.UUID, usages []models.TokenUsage) {
+	var tokens int64
+	for _, usage := range usages {
+		if usage.Model == "" {
+			continue
+		}
+		if err := w.usageRepo.RecordUsage(userID, time.Now(), usage); err != nil {
+			log.Printf("Failed to record LLM usage of user %s: %v", userID, err)
+		}
+		tokens += usage.PromptTokens + usage.CompletionTokens
+	}
+
+	if w.quotas != nil && tokens > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := w.quotas.RecordTokens(ctx, userID, tokens); err != nil {
+			log.Printf("Failed to count tokens of user %s against their quota: %v", userID, err)
+		}
+	}
+}
+
+// consumerID returns the queue consumer name of a worker goroutine. It is
+// unique across processes so leases of different instances never collide.
+func (w *Worker) consumerID(workerID int) string {
+	return fmt.Sprintf("%s-%d", w.instanceID, workerID)
+}
+
+// newInstanceID builds an identifier for this worker process
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8])
 }
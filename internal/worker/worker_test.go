@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"ai-language-notes/internal/ai"
+	"ai-language-notes/internal/api/dto"
+	"ai-language-notes/internal/events"
+	"ai-language-notes/internal/models"
+	"ai-language-notes/internal/queue"
+	"ai-language-notes/internal/repository"
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeNoteRepository keeps a single note in memory
+type fakeNoteRepository struct {
+	repository.NoteRepository
+	note *models.Note
+}
+
+func (r *fakeNoteRepository) ClaimNote(id uuid.UUID, from []models.ProcessingStatus) (bool, error) {
+	r.note.Status = models.StatusProcessing
+	return true, nil
+}
+
+func (r *fakeNoteRepository) GetNoteByID(id uuid.UUID) (*models.Note, error) {
+	note := *r.note
+	return &note, nil
+}
+
+func (r *fakeNoteRepository) UpdateNote(note *models.Note) (*models.Note, error) {
+	r.note = note
+	return note, nil
+}
+
+// fakeUsageRepository stores the recorded usage rows
+type fakeUsageRepository struct {
+	rows []models.TokenUsage
+}
+
+func (r *fakeUsageRepository) RecordUsage(userID uuid.UUID, day time.Time, usage models.TokenUsage) error {
+	r.rows = append(r.rows, usage)
+	return nil
+}
+
+func (r *fakeUsageRepository) GetDailyUsage(userID uuid.UUID, from, to time.Time) ([]*models.UsageDaily, error) {
+	return nil, nil
+}
+
+// fakeLLMService is an LLMService returning a fixed result
+type fakeLLMService struct {
+	content *dto.ProcessedContent
+	err     error
+}
+
+func (s *fakeLLMService) ProcessText(ctx context.Context, text, sourceLanguage, targetLanguage string) (*dto.ProcessedContent, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	content := *s.content
+	return &content, nil
+}
+
+func (s *fakeLLMService) ProcessTextStream(ctx context.Context, text, sourceLanguage, targetLanguage string, onDelta func(string)) (*dto.ProcessedContent, error) {
+	return s.ProcessText(ctx, text, sourceLanguage, targetLanguage)
+}
+
+func TestProcessTaskRecordsUsagePerModelOfFallbackChain(t *testing.T) {
+	failedUsage := models.TokenUsage{Model: "first/model", PromptTokens: 100, CompletionTokens: 20, CostUSD: 0.5}
+	servedUsage := models.TokenUsage{Model: "second/model", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.01}
+	failing := &fakeLLMService{err: &ai.UsageError{
+		Usage: []models.TokenUsage{failedUsage},
+		Err:   ai.NewLLMError(http.StatusServiceUnavailable, "down", "first", true),
+	}}
+	serving := &fakeLLMService{content: &dto.ProcessedContent{Content: "Hallo", Provider: "second", Usage: servedUsage}}
+	llmService := ai.NewFallbackLLMService([]string{"first", "second"}, []ai.LLMService{failing, serving}, ai.CircuitBreakerConfig{FailureThreshold: 100})
+
+	note := &models.Note{ID: uuid.New(), UserID: uuid.New(), OriginalText: "Hallo", Status: models.StatusPending}
+	noteRepo := &fakeNoteRepository{note: note}
+	usageRepo := &fakeUsageRepository{}
+	w := NewWorker(queue.NewMemoryQueue(10, queue.DefaultVisibilityTimeout), noteRepo, nil, usageRepo, nil, llmService, events.NewMemoryBus(), nil, Config{WorkerCount: 1})
+
+	task := &queue.LLMProcessingTask{NoteID: note.ID, UserID: note.UserID, OriginalText: note.OriginalText}
+	if err := w.processTask(task, 0); err != nil {
+		t.Fatalf("processTask: %v", err)
+	}
+
+	// Each model's tokens are recorded under its own row and at its own cost
+	if want := []models.TokenUsage{failedUsage, servedUsage}; !reflect.DeepEqual(usageRepo.rows, want) {
+		t.Fatalf("recorded usage = %+v, want %+v", usageRepo.rows, want)
+	}
+	if noteRepo.note.Status != models.StatusCompleted || noteRepo.note.Usage != servedUsage {
+		t.Fatalf("note = %s with usage %+v, want completed with %+v", noteRepo.note.Status, noteRepo.note.Usage, servedUsage)
+	}
+}
@@ -0,0 +1,2 @@
+-- Admin flag gating the operational endpoints under /api/v1/admin
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- Retry bookkeeping for notes whose LLM processing is rescheduled with backoff
+ALTER TABLE notes ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN next_retry_at TIMESTAMPTZ;
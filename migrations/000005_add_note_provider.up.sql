@@ -0,0 +1,2 @@
+-- The LLM provider that generated a note's content
+ALTER TABLE notes ADD COLUMN provider VARCHAR(50);
@@ -0,0 +1,15 @@
+-- Structured analysis of a note, and its vocabulary for queries across notes
+ALTER TABLE notes ADD COLUMN analysis JSONB;
+
+CREATE TABLE note_vocabulary_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    term TEXT NOT NULL,
+    lemma TEXT NOT NULL,
+    part_of_speech VARCHAR(20) NOT NULL,
+    translation TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_note_vocabulary_entries_note_id ON note_vocabulary_entries(note_id);
+CREATE INDEX idx_note_vocabulary_entries_user_lemma ON note_vocabulary_entries(user_id, lemma);
@@ -0,0 +1,2 @@
+-- The prompt template that generated a note's content, e.g. "analysis/v2/de"
+ALTER TABLE notes ADD COLUMN prompt_version VARCHAR(100);
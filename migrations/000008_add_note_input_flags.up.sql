@@ -0,0 +1,2 @@
+-- Signs of prompt injection found in a note's text, e.g. ["instruction_override"]
+ALTER TABLE notes ADD COLUMN input_flags JSONB;
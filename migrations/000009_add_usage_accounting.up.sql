@@ -0,0 +1,17 @@
+-- Tokens and cost of processing each note, and per user and day
+ALTER TABLE notes ADD COLUMN model VARCHAR(150);
+ALTER TABLE notes ADD COLUMN prompt_tokens BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN completion_tokens BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN cost_usd NUMERIC(14,6) NOT NULL DEFAULT 0;
+
+CREATE TABLE usage_daily (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    model VARCHAR(150) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(14,6) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, day, model)
+);
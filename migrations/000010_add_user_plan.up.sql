@@ -0,0 +1,2 @@
+-- Plan tier of each user, which sets their quotas
+ALTER TABLE users ADD COLUMN plan VARCHAR(20) NOT NULL DEFAULT 'free';
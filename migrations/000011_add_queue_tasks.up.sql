@@ -0,0 +1,22 @@
+-- Processing tasks of the Postgres queue backend
+CREATE TABLE queue_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    lane VARCHAR(20) NOT NULL DEFAULT 'interactive',
+    user_seq BIGINT NOT NULL DEFAULT 0,
+    payload JSONB NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_queue_tasks_note_id ON queue_tasks(note_id);
+CREATE INDEX idx_queue_tasks_user_lane ON queue_tasks(user_id, lane);
+CREATE INDEX idx_queue_tasks_state_available ON queue_tasks(state, available_at);
+-- At most one live task per note
+CREATE UNIQUE INDEX idx_queue_tasks_live_note ON queue_tasks(note_id) WHERE state <> 'dead';